@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// archiveCache caches the contents of the dated archive files, keyed by path
+// and revalidated against the file's modification time so today's archive is
+// picked up after every write.
+type archiveCache struct {
+	mu      sync.Mutex
+	entries map[string]archiveCacheEntry
+}
+
+type archiveCacheEntry struct {
+	modTime time.Time
+	data    []byte
+}
+
+func newArchiveCache() *archiveCache {
+	return &archiveCache{entries: make(map[string]archiveCacheEntry)}
+}
+
+// read returns the contents of the file at path. It returns an error wrapping
+// os.ErrNotExist when the file is missing.
+func (c *archiveCache) read(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.data, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[path] = archiveCacheEntry{modTime: info.ModTime(), data: data}
+	c.mu.Unlock()
+	return data, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// archivedPeriod holds the cutoffs a live run would have left in one archive
+// file: every region's cutoffs as of the last cycle recorded for it in the
+// period.
+type archivedPeriod struct {
+	start   time.Time
+	regions map[string]cutoff.RegionData
+}
+
+// archivedPeriods rebuilds the archive periods from every row in the
+// database, in chronological order. Only the cutoff values are stored, so the
+// ladder sizes and other details of the live files are left empty.
+func (d *cutoffDB) archivedPeriods(ctx context.Context, layout output.ArchiveLayout) ([]archivedPeriod, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT recorded_at, region, queue, tier, cutoff_lp FROM cutoffs ORDER BY recorded_at`)
+	if err != nil {
+		return nil, fmt.Errorf("query cutoffs: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []archivedPeriod
+	for rows.Next() {
+		var recordedAt string
+		var region string
+		var value cutoff.Value
+		if err := rows.Scan(&recordedAt, &region, &value.Queue, &value.Tier, &value.LP); err != nil {
+			return nil, fmt.Errorf("scan cutoffs: %w", err)
+		}
+		at, err := time.Parse(time.RFC3339, recordedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse recorded_at %q: %w", recordedAt, err)
+		}
+
+		start := layout.PeriodStart(at)
+		if len(periods) == 0 || !periods[len(periods)-1].start.Equal(start) {
+			periods = append(periods, archivedPeriod{start: start, regions: make(map[string]cutoff.RegionData)})
+		}
+		regions := periods[len(periods)-1].regions
+		data := regions[region]
+		if !data.UpdatedAt.Equal(at) {
+			// A later cycle replaces the region's cutoffs, just like it
+			// replaced the archive file.
+			data = cutoff.RegionData{UpdatedAt: at}
+		}
+		regions[region] = withValue(data, value, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read cutoffs: %w", err)
+	}
+	return periods, nil
+}
+
+// withValue sets one recorded cutoff in data. A queue with a Challenger row
+// but no Grandmaster row was computed in minimal mode.
+func withValue(data cutoff.RegionData, value cutoff.Value, at time.Time) cutoff.RegionData {
+	queue := &data.RANKED_SOLO_5x5
+	if value.Queue == cutoff.QueueFlex {
+		queue = &data.RANKED_FLEX_SR
+	}
+	if !queue.Computed() {
+		queue.UpdatedAt = at
+		queue.ChallengerOnly = true
+	}
+	switch value.Tier {
+	case cutoff.TierChallenger:
+		queue.Challenger = value.LP
+	case cutoff.TierGrandmaster:
+		queue.Grandmaster = value.LP
+		queue.ChallengerOnly = false
+	}
+	return data
+}
+
+// runBackfill rebuilds the archive of the configured output directory from
+// the database at DB_PATH.
+func runBackfill(ctx context.Context, s settings, force bool) error {
+	if s.DBPath == "" {
+		return errors.New("backfill needs DB_PATH")
+	}
+	if _, err := os.Stat(s.DBPath); err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	db, err := openCutoffDB(s.DBPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return backfillArchives(ctx, db, s.outputOptions(), force)
+}
+
+// backfillArchives rewrites the dated archive files from the database in the
+// format of the live writes. Files that already exist are left alone unless
+// force is set, so running it again changes nothing.
+func backfillArchives(ctx context.Context, db *cutoffDB, opts output.Options, force bool) error {
+	periods, err := db.archivedPeriods(ctx, opts.Archive)
+	if err != nil {
+		return err
+	}
+
+	var written, skipped int
+	for _, period := range periods {
+		path := opts.Archive.Path(opts.Dir, period.start)
+		if _, err := os.Stat(path); err == nil && !force {
+			skipped++
+			continue
+		}
+		jsonData, err := opts.Marshal(output.File{SchemaVersion: output.SchemaVersion, Regions: period.regions})
+		if err != nil {
+			return fmt.Errorf("marshal JSON: %w", err)
+		}
+		if err := output.EnsureDir(filepath.Dir(path)); err != nil {
+			return err
+		}
+		if err := output.WriteFile(path, jsonData); err != nil {
+			return err
+		}
+		slog.Info("Backfilled archive", "path", path, "regions", len(period.regions))
+		written++
+	}
+	slog.Info("Backfill finished", "written", written, "skipped", skipped)
+	return nil
+}
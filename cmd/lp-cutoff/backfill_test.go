@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// readArchive decodes the archive file at path.
+func readArchive(t *testing.T, path string) output.File {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file output.File
+	if err := json.Unmarshal(raw, &file); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestBackfillArchives(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	day := time.Date(2024, time.March, 30, 0, 0, 0, 0, time.UTC)
+	cycles := []struct {
+		at         time.Time
+		outputData map[string]cutoff.RegionData
+	}{
+		{day.Add(10 * time.Hour), map[string]cutoff.RegionData{"euw1": regionData(900, 400), "kr": regionData(1100, 600)}},
+		{day.Add(22 * time.Hour), map[string]cutoff.RegionData{"euw1": regionData(910, 410)}},
+		{day.Add(34 * time.Hour), map[string]cutoff.RegionData{"euw1": regionData(920, 420), "kr": regionData(1150, 650)}},
+	}
+	for _, cycle := range cycles {
+		if err := db.record(ctx, cycle.at, cycle.outputData); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	layout, err := output.NewArchiveLayout(output.DefaultArchiveLayout, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := output.Options{Dir: t.TempDir(), Archive: layout}
+	if err := backfillArchives(ctx, db, opts, false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string][2]int{
+		"2024-03-30": {"euw1": {910, 410}, "kr": {1100, 600}},
+		"2024-03-31": {"euw1": {920, 420}, "kr": {1150, 650}},
+	}
+	for date, regions := range want {
+		file := readArchive(t, filepath.Join(opts.Dir, date, "cutoffs.json"))
+		if file.SchemaVersion != output.SchemaVersion {
+			t.Errorf("%s schemaVersion = %d, want %d", date, file.SchemaVersion, output.SchemaVersion)
+		}
+		if len(file.Regions) != len(regions) {
+			t.Errorf("%s regions = %v, want %d", date, file.Regions, len(regions))
+		}
+		for region, cutoffs := range regions {
+			for queue, c := range map[string]cutoff.Cutoffs{"solo/duo": file.Regions[region].RANKED_SOLO_5x5, "flex": file.Regions[region].RANKED_FLEX_SR} {
+				if [2]int{c.Challenger, c.Grandmaster} != cutoffs {
+					t.Errorf("%s %s %s cutoffs = %d/%d, want %v", date, region, queue, c.Challenger, c.Grandmaster, cutoffs)
+				}
+			}
+		}
+	}
+
+	// An archive that exists is kept, unless forced.
+	existing := filepath.Join(opts.Dir, "2024-03-31", "cutoffs.json")
+	if err := os.WriteFile(existing, []byte(`{"schemaVersion":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillArchives(ctx, db, opts, false); err != nil {
+		t.Fatal(err)
+	}
+	if file := readArchive(t, existing); len(file.Regions) != 0 {
+		t.Errorf("existing archive was rewritten without force: %v", file.Regions)
+	}
+	if err := backfillArchives(ctx, db, opts, true); err != nil {
+		t.Fatal(err)
+	}
+	if file := readArchive(t, existing); len(file.Regions) != 2 {
+		t.Errorf("existing archive regions after a forced backfill = %v, want both", file.Regions)
+	}
+}
+
+func TestRunBackfillNeedsDatabase(t *testing.T) {
+	if err := runBackfill(context.Background(), settings{}, false); err == nil {
+		t.Error("runBackfill without DB_PATH succeeded, want an error")
+	}
+	s := settings{DBPath: filepath.Join(t.TempDir(), "missing.db")}
+	if err := runBackfill(context.Background(), s, false); err == nil {
+		t.Error("runBackfill with a missing database succeeded, want an error")
+	}
+	if _, err := os.Stat(s.DBPath); err == nil {
+		t.Error("runBackfill created the missing database")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// cutoffChange describes how a single cutoff moved between two cycles.
+type cutoffChange struct {
+	Region    string `json:"region"`
+	Queue     string `json:"queue"`
+	Tier      string `json:"tier"`
+	Previous  int    `json:"previous"`
+	Current   int    `json:"current"`
+	Delta     int    `json:"delta"`
+	Direction string `json:"direction"`
+}
+
+// diffCutoffs returns the non-zero cutoff changes from previous to current,
+// sorted by region, queue and tier. Regions and queues missing from either
+// side are skipped.
+func diffCutoffs(previous, current map[string]cutoff.RegionData) []cutoffChange {
+	var changes []cutoffChange
+	for region, curr := range current {
+		prev, ok := previous[region]
+		if !ok {
+			continue
+		}
+		prevLP := make(map[cutoff.Value]int)
+		for _, value := range prev.Values() {
+			prevLP[cutoff.Value{Queue: value.Queue, Tier: value.Tier}] = value.LP
+		}
+		for _, value := range curr.Values() {
+			if lp, ok := prevLP[cutoff.Value{Queue: value.Queue, Tier: value.Tier}]; ok {
+				changes = appendChange(changes, region, value.Queue, value.Tier, lp, value.LP)
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.Queue != b.Queue {
+			return a.Queue < b.Queue
+		}
+		return a.Tier < b.Tier
+	})
+	return changes
+}
+
+func appendChange(changes []cutoffChange, region, queue, tier string, previous, current int) []cutoffChange {
+	delta := current - previous
+	if delta == 0 {
+		return changes
+	}
+	direction := "up"
+	if delta < 0 {
+		direction = "down"
+	}
+	return append(changes, cutoffChange{
+		Region:    region,
+		Queue:     queue,
+		Tier:      tier,
+		Previous:  previous,
+		Current:   current,
+		Delta:     delta,
+		Direction: direction,
+	})
+}
+
+// writeChangesFile writes the cycle's change report to current/changes.json.
+func writeChangesFile(outputDir string, changes []cutoffChange) error {
+	if changes == nil {
+		changes = []cutoffChange{}
+	}
+	jsonData, err := json.MarshalIndent(changes, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshal changes JSON: %w", err)
+	}
+
+	currentDir := filepath.Join(outputDir, "current")
+	if err := output.EnsureDir(currentDir); err != nil {
+		return err
+	}
+	return output.WriteFile(filepath.Join(currentDir, "changes.json"), jsonData)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// tierChurn counts the players who crossed a tier's cutoff since the previous
+// cycle: promotions are now at or above the cutoff but were not before,
+// demotions the reverse.
+type tierChurn struct {
+	Region     string `json:"region"`
+	Queue      string `json:"queue"`
+	Tier       string `json:"tier"`
+	Promotions int    `json:"promotions"`
+	Demotions  int    `json:"demotions"`
+}
+
+// regionChurn returns the churn of every computed queue and tier of current
+// against previous. Without a previous cycle every count is zero.
+func regionChurn(region string, previous *cutoff.RegionData, current cutoff.RegionData) []tierChurn {
+	var churn []tierChurn
+	queues := []struct {
+		name    string
+		current cutoff.Cutoffs
+		before  func(cutoff.RegionData) cutoff.Cutoffs
+	}{
+		{cutoff.QueueSoloDuo, current.RANKED_SOLO_5x5, func(d cutoff.RegionData) cutoff.Cutoffs { return d.RANKED_SOLO_5x5 }},
+		{cutoff.QueueFlex, current.RANKED_FLEX_SR, func(d cutoff.RegionData) cutoff.Cutoffs { return d.RANKED_FLEX_SR }},
+	}
+	for _, q := range queues {
+		if !q.current.Computed() {
+			continue
+		}
+		var before cutoff.TierPlayers
+		if previous != nil {
+			before = q.before(*previous).Players
+		}
+		churn = append(churn, playerChurn(region, q.name, cutoff.TierChallenger, before.Challenger, q.current.Players.Challenger))
+		if !q.current.ChallengerOnly {
+			churn = append(churn, playerChurn(region, q.name, cutoff.TierGrandmaster, before.Grandmaster, q.current.Players.Grandmaster))
+		}
+	}
+	return churn
+}
+
+// playerChurn diffs the players at or above a cutoff. A nil before means there
+// is no baseline, which counts as no churn.
+func playerChurn(region, queue, tier string, before, after map[string]bool) tierChurn {
+	churn := tierChurn{Region: region, Queue: queue, Tier: tier}
+	if before == nil {
+		return churn
+	}
+	for id := range after {
+		if !before[id] {
+			churn.Promotions++
+		}
+	}
+	for id := range before {
+		if !after[id] {
+			churn.Demotions++
+		}
+	}
+	return churn
+}
+
+// writeChurnFile writes the cycle's churn to current/churn.json.
+func writeChurnFile(outputDir string, churn []tierChurn) error {
+	if churn == nil {
+		churn = []tierChurn{}
+	}
+	jsonData, err := json.MarshalIndent(churn, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshal churn JSON: %w", err)
+	}
+
+	currentDir := filepath.Join(outputDir, "current")
+	if err := output.EnsureDir(currentDir); err != nil {
+		return err
+	}
+	return output.WriteFile(filepath.Join(currentDir, "churn.json"), jsonData)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// playerSet returns the set of ids.
+func playerSet(ids ...string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+func TestRegionChurn(t *testing.T) {
+	before := regionData(900, 400)
+	before.RANKED_SOLO_5x5.Players = cutoff.TierPlayers{
+		Challenger:  playerSet("a", "b", "c"),
+		Grandmaster: playerSet("a", "b", "c", "d", "e"),
+	}
+	after := regionData(910, 410)
+	// d replaced c in Challenger and f replaced e among the players at or
+	// above the Grandmaster cutoff.
+	after.RANKED_SOLO_5x5.Players = cutoff.TierPlayers{
+		Challenger:  playerSet("a", "b", "d"),
+		Grandmaster: playerSet("a", "b", "c", "d", "f"),
+	}
+	after.RANKED_FLEX_SR = cutoff.Cutoffs{}
+
+	tests := []struct {
+		name     string
+		previous *cutoff.RegionData
+		want     []tierChurn
+	}{
+		{
+			"against the previous cycle",
+			&before,
+			[]tierChurn{
+				{Region: "euw1", Queue: cutoff.QueueSoloDuo, Tier: cutoff.TierChallenger, Promotions: 1, Demotions: 1},
+				{Region: "euw1", Queue: cutoff.QueueSoloDuo, Tier: cutoff.TierGrandmaster, Promotions: 1, Demotions: 1},
+			},
+		},
+		{
+			"first cycle",
+			nil,
+			[]tierChurn{
+				{Region: "euw1", Queue: cutoff.QueueSoloDuo, Tier: cutoff.TierChallenger},
+				{Region: "euw1", Queue: cutoff.QueueSoloDuo, Tier: cutoff.TierGrandmaster},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := regionChurn("euw1", tt.previous, after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("churn = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionChurnChallengerOnly(t *testing.T) {
+	before := regionData(900, 0)
+	before.RANKED_SOLO_5x5.Players.Challenger = playerSet("a", "b")
+	after := regionData(900, 0)
+	after.RANKED_SOLO_5x5.ChallengerOnly = true
+	after.RANKED_SOLO_5x5.Players.Challenger = playerSet("a", "c")
+	after.RANKED_FLEX_SR = cutoff.Cutoffs{}
+
+	want := []tierChurn{{Region: "euw1", Queue: cutoff.QueueSoloDuo, Tier: cutoff.TierChallenger, Promotions: 1, Demotions: 1}}
+	if got := regionChurn("euw1", &before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("churn = %+v, want %+v", got, want)
+	}
+}
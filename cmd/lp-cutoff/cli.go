@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// command is a subcommand of the binary, run as "lp-cutoff <name> [flags]".
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+var commands = []command{
+	{"serve", "fetch, compute and publish the cutoffs in a loop (default)", runServe},
+	{"fetch", "compute the cutoffs once and print them as JSON", runFetch},
+	{"validate-config", "check a cutoffs config file and report every problem", runValidateConfig},
+	{"history", "print a region's archived cutoffs as JSON", runHistory},
+	{"backfill", "rebuild the dated archive files from the database", runBackfillCommand},
+}
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(args)
+			return
+		}
+	}
+	if name != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	}
+	usage()
+	if name != "help" {
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -h for the flags of a command. Only serve polls, so only it\ntakes -interval. Everything else is configured through the environment.\n", os.Args[0])
+}
+
+// commonFlags override the settings from the environment that every command
+// may need to point elsewhere. -output is common to all commands; -regions and
+// -config only to those that fetch regions. Only serve runs cycles in a loop,
+// so it alone takes -interval: fetch computes the cutoffs once, and history
+// and backfill only read what earlier cycles recorded.
+type commonFlags struct {
+	regions string
+	output  string
+	config  string
+}
+
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	f := &commonFlags{}
+	fs.StringVar(&f.output, "output", "", "output directory, overriding OUTPUT_DIR")
+	return f
+}
+
+// addFetchFlags adds -regions and -config to the flags of a command that
+// fetches regions from the cutoffs config.
+func (f *commonFlags) addFetchFlags(fs *flag.FlagSet) {
+	fs.StringVar(&f.regions, "regions", "", "comma-separated regions to process, overriding REGIONS")
+	fs.StringVar(&f.config, "config", "", "cutoffs config file to load instead of the embedded cutoffs.yaml, overriding CUTOFFS_CONFIG")
+}
+
+func (f *commonFlags) apply(s *settings) {
+	if f.regions != "" {
+		s.Regions = splitList(f.regions)
+	}
+	if f.output != "" {
+		s.OutputDir = f.output
+	}
+	if f.config != "" {
+		s.ConfigPath = f.config
+	}
+}
+
+// loadCommandSettings loads the settings, applies the command's flags and
+// installs the configured logger, exiting on invalid settings. needAPIKey
+// makes a missing Riot API key an error.
+func loadCommandSettings(common *commonFlags, needAPIKey bool) settings {
+	s, err := loadSettings()
+	if err == nil && common != nil {
+		common.apply(&s)
+	}
+	if err == nil && needAPIKey {
+		err = s.requireAPIKey()
+	}
+	if err != nil {
+		slog.Error("Invalid settings", "error", err)
+		os.Exit(1)
+	}
+
+	logger, err := newLogger(os.Stderr, s.LogLevel, s.LogFormat)
+	if err != nil {
+		slog.Error("Invalid logging settings", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	return s
+}
+
+// runFetch computes the cutoffs of every configured region once and prints
+// them to stdout in the format of cutoffs.json, without writing, uploading or
+// sending anything. It exits non-zero when a region failed.
+func runFetch(args []string) {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	common.addFetchFlags(fs)
+	fs.Parse(args)
+	s := loadCommandSettings(common, true)
+
+	watcher, err := newConfigWatcher(s.ConfigPath, s.Regions, s.StrictConfig)
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+	u := newUpdater(s, watcher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.CycleTimeout)
+	outputData, report := u.collect(ctx, watcher.cfg, true)
+	cancel()
+
+	jsonData, err := json.MarshalIndent(output.File{SchemaVersion: output.SchemaVersion, Regions: outputData}, "", "    ")
+	if err != nil {
+		slog.Error("Encoding cutoffs failed", "error", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonData))
+	if failed := report.failedRegions(); len(failed) > 0 {
+		slog.Error("Regions failed", "regions", failed)
+		os.Exit(1)
+	}
+}
+
+// runValidateConfig loads the config file named by its argument, or by
+// CUTOFFS_CONFIG or CONFIG_PATH, or else the embedded one, reporting every
+// problem in it.
+func runValidateConfig(args []string) {
+	fs := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s validate-config [path]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := configPathFromEnv()
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("config is valid: %d regions (%s)\n", len(cfg.Regions), strings.Join(cfg.regionNames(), ", "))
+}
+
+// runHistory prints a region's cutoffs archived in the output directory over
+// the last days, like the /cutoffs/history endpoint.
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "region whose history to print (required)")
+	days := fs.Int("days", 30, "number of days up to today to include")
+	fs.Parse(args)
+	s := loadCommandSettings(common, false)
+
+	if *region == "" {
+		fmt.Fprintln(os.Stderr, "-region is required")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *days < 1 || *days > maxHistoryDays {
+		fmt.Fprintf(os.Stderr, "-days must be between 1 and %d, got %d\n", maxHistoryDays, *days)
+		os.Exit(2)
+	}
+
+	history, err := readHistory(os.ReadFile, s.Archive, s.OutputDir, normalizePlatform(*region), *days, time.Now())
+	if err != nil {
+		slog.Error("Reading archive failed", "error", err)
+		os.Exit(1)
+	}
+	jsonData, err := json.MarshalIndent(history, "", "    ")
+	if err != nil {
+		slog.Error("Encoding history failed", "error", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonData))
+}
+
+// runBackfillCommand rebuilds the dated archive files from the database at
+// DB_PATH, see backfillArchives.
+func runBackfillCommand(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	force := fs.Bool("force", false, "also overwrite archive files that already exist")
+	fs.Parse(args)
+	s := loadCommandSettings(common, false)
+
+	if err := runBackfill(context.Background(), s, *force); err != nil {
+		slog.Error("Backfill failed", "error", err)
+		os.Exit(1)
+	}
+}
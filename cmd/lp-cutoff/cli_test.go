@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// captureStdout returns what run prints to stdout.
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	printed := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		printed <- string(data)
+	}()
+	run()
+	w.Close()
+	return <-printed
+}
+
+func TestCommandFlags(t *testing.T) {
+	riot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"entries":[{"puuid":"a","leaguePoints":1500},{"puuid":"b","leaguePoints":1400},{"puuid":"c","leaguePoints":1300},{"puuid":"d","leaguePoints":1200},{"puuid":"e","leaguePoints":1100}]}`)
+	}))
+	defer riot.Close()
+	logger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(logger) })
+
+	configPath := writeConfig(t, slotsYAML("euw1")+slotsYAML("kr"))
+	t.Setenv("RIOT_API_KEY", "test-key")
+	t.Setenv("RIOT_BASE_URL", riot.URL)
+	t.Setenv("SKIP_PREFLIGHT", "true")
+	t.Setenv("REGION_START_JITTER", "0s")
+	// OUTPUT_DIR points elsewhere, so only -output finds the files.
+	t.Setenv("OUTPUT_DIR", filepath.Join(t.TempDir(), "env"))
+	layout, err := output.NewArchiveLayout(output.DefaultArchiveLayout, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("serve", func(t *testing.T) {
+		dir := t.TempDir()
+		runServe([]string{"-once", "-regions", "kr", "-config", configPath, "-output", dir, "-interval", "30s"})
+		file := readArchive(t, filepath.Join(dir, "current", "cutoffs.json"))
+		if got := slices.Sorted(maps.Keys(file.Regions)); !slices.Equal(got, []string{"kr"}) {
+			t.Errorf("published regions = %v, want [kr] from -regions", got)
+		}
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		printed := captureStdout(t, func() { runFetch([]string{"-regions", "euw1", "-config", configPath}) })
+		var file output.File
+		if err := json.Unmarshal([]byte(printed), &file); err != nil {
+			t.Fatalf("decode %q: %v", printed, err)
+		}
+		if got := slices.Sorted(maps.Keys(file.Regions)); !slices.Equal(got, []string{"euw1"}) {
+			t.Errorf("printed regions = %v, want [euw1] from -regions", got)
+		}
+	})
+
+	t.Run("validate-config", func(t *testing.T) {
+		printed := captureStdout(t, func() { runValidateConfig([]string{configPath}) })
+		if want := "config is valid: 2 regions (euw1, kr)"; !strings.Contains(printed, want) {
+			t.Errorf("printed %q, want %q", printed, want)
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := output.Write(output.Options{Dir: dir, Archive: layout}, map[string]cutoff.RegionData{"euw1": regionData(900, 400)}); err != nil {
+			t.Fatal(err)
+		}
+		printed := captureStdout(t, func() { runHistory([]string{"-output", dir, "-region", "euw1", "-days", "1"}) })
+		var history []historyPoint
+		if err := json.Unmarshal([]byte(printed), &history); err != nil {
+			t.Fatalf("decode %q: %v", printed, err)
+		}
+		if len(history) != 1 || history[0].Data.RANKED_SOLO_5x5.Challenger != 900 {
+			t.Errorf("history = %+v, want today's archive from -output", history)
+		}
+	})
+
+	t.Run("backfill", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "cutoffs.db")
+		db, err := openCutoffDB(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		day := time.Date(2024, time.March, 30, 12, 0, 0, 0, time.UTC)
+		if err := db.record(context.Background(), day, map[string]cutoff.RegionData{"euw1": regionData(900, 400)}); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		t.Setenv("DB_PATH", dbPath)
+
+		dir := t.TempDir()
+		runBackfillCommand([]string{"-output", dir})
+		if _, err := os.Stat(layout.Path(dir, day)); err != nil {
+			t.Errorf("archive not rebuilt under -output: %v", err)
+		}
+	})
+}
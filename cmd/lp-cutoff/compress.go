@@ -0,0 +1,81 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipped compresses the responses of h with gzip for clients whose
+// Accept-Encoding allows it, and passes them through unchanged otherwise.
+func gzipped(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			h(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		h(gw, r)
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows gzip,
+// either by name or through "*", with a non-zero quality.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if quality, err := strconv.ParseFloat(q, 64); err == nil && quality == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter compresses everything written through it. The gzip
+// stream is only started once the status is known, so bodiless responses
+// such as 304s stay bodiless.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	zw          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status != http.StatusNoContent && status != http.StatusNotModified {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.zw = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.zw == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.zw.Write(p)
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.zw != nil {
+		w.zw.Close()
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip;q=0.8", true},
+		{"br, *", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0, deflate", false},
+		{"identity", false},
+		{"*;q=0", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %t, want %t", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestGzipped(t *testing.T) {
+	const body = `{"euw1":{"challenger":900}}`
+	h := gzipped(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("unchanged") {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Length", "27")
+		io.WriteString(w, body)
+	})
+	serve := func(target, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	t.Run("gzip accepted", func(t *testing.T) {
+		w := serve("/cutoffs", "br, gzip")
+		if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", got)
+		}
+		if got := w.Header().Get("Content-Length"); got != "" {
+			t.Errorf("Content-Length = %q, want it dropped", got)
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := io.ReadAll(zr); err != nil || string(got) != body {
+			t.Errorf("decompressed body = %q, %v, want %q", got, err, body)
+		}
+	})
+
+	t.Run("gzip not accepted", func(t *testing.T) {
+		w := serve("/cutoffs", "gzip;q=0")
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if w.Body.String() != body {
+			t.Errorf("body = %q, want %q", w.Body, body)
+		}
+	})
+
+	t.Run("not modified stays bodiless", func(t *testing.T) {
+		w := serve("/cutoffs?unchanged", "gzip")
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("response = %d %q with Content-Encoding %q, want a bare 304", w.Code, w.Body, w.Header().Get("Content-Encoding"))
+		}
+	})
+
+	for _, acceptEncoding := range []string{"gzip", ""} {
+		if vary := serve("/cutoffs", acceptEncoding).Header().Values("Vary"); !slices.Contains(vary, "Accept-Encoding") {
+			t.Errorf("Vary with Accept-Encoding %q = %v, want Accept-Encoding", acceptEncoding, vary)
+		}
+	}
+}
@@ -0,0 +1,569 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+type config struct {
+	Regions map[string]cutoff.Queues `yaml:",inline"`
+	// PollInterval is the poll interval set by the top-level poll_interval
+	// key, zero when unset.
+	PollInterval time.Duration `yaml:"-"`
+}
+
+// pollIntervalKey is the top-level config key setting the poll interval; every
+// other top-level key is a region.
+const pollIntervalKey = "poll_interval"
+
+//go:embed cutoffs.yaml
+var cutoffsYAML []byte
+
+// loadConfig reads the cutoffs config from path, falling back to the embedded
+// cutoffs.yaml when path is empty, and applies the LPCUTOFF_* overrides from
+// the environment. Unless strict is set, a region that fails
+// to decode or validate is logged and skipped so one bad block doesn't take
+// down the others; a document that doesn't parse at all, or leaves no valid
+// region, is always an error.
+func loadConfig(path string, strict bool) (config, error) {
+	data := cutoffsYAML
+	if path != "" {
+		fileData, err := os.ReadFile(path)
+		if err != nil {
+			return config{}, fmt.Errorf("read config file %s: %w", path, err)
+		}
+		data = fileData
+	}
+
+	name := path
+	if name == "" {
+		name = "embedded cutoffs.yaml"
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return config{}, fmt.Errorf("unmarshal %s: %w", name, err)
+	}
+	root := parseConfigNodes(data)
+	problems := duplicateKeys(root)
+	doc, overrides, envProblems := applyEnvOverrides(doc, os.Environ())
+	problems = append(problems, envProblems...)
+	doc, pollInterval, err := takePollInterval(doc)
+	if err != nil {
+		problems = append(problems, problemAt(err, pollIntervalKey))
+	}
+	cfg, invalid := decodeRegions(doc, root, overrides)
+	cfg.PollInterval = pollInterval
+	cfg, normalizeProblems := normalizeRegions(cfg)
+	problems = append(problems, normalizeProblems...)
+	cfg = inheritFloors(cfg)
+	if strict {
+		problems = append(problems, invalid...)
+	} else {
+		for _, region := range cfg.regionNames() {
+			if regionProblems := validateRegion(region, cfg.Regions[region]); len(regionProblems) > 0 {
+				invalid = append(invalid, regionProblems...)
+				delete(cfg.Regions, region)
+			}
+		}
+		for _, err := range locateProblems(root, overrides, invalid) {
+			slog.Warn("Skipping invalid config region", "config", name, "error", err)
+		}
+		if len(cfg.Regions) == 0 {
+			problems = append(problems, invalid...)
+		}
+	}
+	problems = append(problems, validateConfig(cfg)...)
+	if len(problems) > 0 {
+		return config{}, fmt.Errorf("invalid config %s:\n%w", name, errors.Join(locateProblems(root, overrides, problems)...))
+	}
+	return cfg, nil
+}
+
+// takePollInterval removes the poll_interval key from the config document and
+// parses it, returning zero when the key is missing.
+func takePollInterval(doc yaml.MapSlice) (yaml.MapSlice, time.Duration, error) {
+	regions := make(yaml.MapSlice, 0, len(doc))
+	var interval time.Duration
+	var err error
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) != pollIntervalKey {
+			regions = append(regions, item)
+			continue
+		}
+		value, ok := item.Value.(string)
+		if !ok {
+			err = fmt.Errorf("%s must be a duration such as \"2m\", got %v", pollIntervalKey, item.Value)
+			continue
+		}
+		if interval, err = time.ParseDuration(value); err != nil {
+			err = fmt.Errorf("%s: %w", pollIntervalKey, err)
+		} else if interval < minPollIntervalFloor {
+			err = fmt.Errorf("%s must be at least %s, got %s", pollIntervalKey, minPollIntervalFloor, interval)
+			interval = 0
+		}
+	}
+	return regions, interval, err
+}
+
+// decodeRegions decodes every region of the config document on its own,
+// returning the regions that decoded and an error for each that didn't or
+// leaves out a queue. The values from the file are decoded from their node in
+// root, so type errors carry their line, and the overridden values on top of
+// them, so type errors name their variable.
+func decodeRegions(doc yaml.MapSlice, root *yamlv3.Node, overrides map[string]string) (config, []error) {
+	cfg := config{Regions: make(map[string]cutoff.Queues, len(doc))}
+	var problems []error
+	for _, item := range doc {
+		region := fmt.Sprint(item.Key)
+		if missing := missingQueues(item.Value); len(missing) > 0 {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: missing the %s queue, add it with its slots or with enabled: false",
+				region, strings.Join(missing, " and ")), region))
+			continue
+		}
+		if root == nil {
+			// Without nodes, decode the block as is and report errors
+			// without lines.
+			queues, err := decodeBlock(item.Value)
+			if err != nil {
+				problems = append(problems, problemAt(fmt.Errorf("region %q: %w", region, err), region))
+				continue
+			}
+			cfg.Regions[region] = queues
+			continue
+		}
+
+		names := regionOverrides(overrides, region)
+		paths := make([][]string, len(names))
+		for i, name := range names {
+			paths[i] = strings.Split(strings.ToLower(strings.TrimPrefix(name, configEnvPrefix)), "__")[1:]
+		}
+		var queues cutoff.Queues
+		var regionProblems []error
+		if node := mappingValue(root, func(key string) bool { return key == region }); node != nil {
+			// The overridden values don't have to decode.
+			block := node[1]
+			for _, path := range paths {
+				block = withoutKey(block, path)
+			}
+			regionProblems = typeProblems(block.Decode(&queues), region, "")
+		}
+		for i, name := range names {
+			path := paths[i]
+			value := configValue(item.Value, path)
+			for i := len(path) - 1; i >= 0; i-- {
+				value = map[string]any{path[i]: value}
+			}
+			var node yamlv3.Node
+			err := node.Encode(value)
+			if err == nil {
+				err = node.Decode(&queues)
+			}
+			regionProblems = append(regionProblems, typeProblems(err, region, name)...)
+		}
+		if len(regionProblems) > 0 {
+			problems = append(problems, regionProblems...)
+			continue
+		}
+		cfg.Regions[region] = queues
+	}
+	return cfg, problems
+}
+
+// decodeBlock decodes a region block of the config document.
+func decodeBlock(block any) (cutoff.Queues, error) {
+	var queues cutoff.Queues
+	data, err := yaml.Marshal(block)
+	if err == nil {
+		err = yaml.Unmarshal(data, &queues)
+	}
+	return queues, err
+}
+
+// regionOverrides returns the sorted variables overriding a value of region,
+// from the overrides returned by applyEnvOverrides.
+func regionOverrides(overrides map[string]string, region string) []string {
+	var names []string
+	for path, name := range overrides {
+		if platform, _, nested := strings.Cut(path, "."); nested && platform == normalizePlatform(region) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configValue returns the value at path in a block of the config document,
+// nil when it is missing.
+func configValue(block any, path []string) any {
+	for _, key := range path {
+		items, _ := block.(yaml.MapSlice)
+		block = nil
+		for _, item := range items {
+			if fmt.Sprint(item.Key) == key {
+				block = item.Value
+				break
+			}
+		}
+	}
+	return block
+}
+
+// missingQueues returns the queues a region block doesn't configure. Blocks
+// that aren't a mapping are left to fail decoding.
+func missingQueues(block any) []string {
+	keys, ok := block.(yaml.MapSlice)
+	if !ok {
+		return nil
+	}
+	var missing []string
+	for _, queue := range []string{"solo_duo", "flex"} {
+		if !slices.ContainsFunc(keys, func(item yaml.MapItem) bool { return fmt.Sprint(item.Key) == queue }) {
+			missing = append(missing, queue)
+		}
+	}
+	return missing
+}
+
+// normalizeRegions rewrites region keys to their canonical platform code,
+// resolving aliases such as "euw" to "euw1". Keys that collapse onto the same
+// platform are reported as problems.
+func normalizeRegions(cfg config) (config, []error) {
+	var problems []error
+	normalized := make(map[string]cutoff.Queues, len(cfg.Regions))
+	sources := make(map[string]string, len(cfg.Regions))
+	for _, region := range cfg.regionNames() {
+		platform := normalizePlatform(region)
+		if platform != region {
+			slog.Warn("Config region is an alias", "region", region, "platform", platform)
+		}
+		if other, ok := sources[platform]; ok {
+			problems = append(problems, problemAt(fmt.Errorf("regions %q and %q both refer to platform %q", other, region, platform), region))
+			continue
+		}
+		sources[platform] = region
+		normalized[platform] = cfg.Regions[region]
+	}
+
+	cfg.Regions = normalized
+	return cfg, problems
+}
+
+// inheritFloors copies the region-level LP floors into every queue that
+// doesn't set its own.
+func inheritFloors(cfg config) config {
+	for region, queues := range cfg.Regions {
+		for _, q := range []*cutoff.QueueConfig{&queues.SoloDuo, &queues.Flex} {
+			if q.MinChallengerLP == nil {
+				q.MinChallengerLP = queues.MinChallengerLP
+			}
+			if q.MinGrandmasterLP == nil {
+				q.MinGrandmasterLP = queues.MinGrandmasterLP
+			}
+		}
+		cfg.Regions[region] = queues
+	}
+	return cfg
+}
+
+// filterRegions restricts cfg to regions, accepting the same aliases as the
+// config file. Every listed region must be configured; an empty list keeps
+// all of them.
+func filterRegions(cfg config, regions []string) (config, error) {
+	if len(regions) == 0 {
+		return cfg, nil
+	}
+
+	filtered := config{Regions: make(map[string]cutoff.Queues, len(regions))}
+	var missing []string
+	for _, region := range regions {
+		platform := normalizePlatform(region)
+		queues, ok := cfg.Regions[platform]
+		if !ok {
+			missing = append(missing, region)
+			continue
+		}
+		filtered.Regions[platform] = queues
+	}
+	if len(missing) > 0 {
+		return config{}, fmt.Errorf("REGIONS lists regions missing from the config: %s", strings.Join(missing, ", "))
+	}
+	return filtered, nil
+}
+
+// baseURLs returns the base URL override of every region that sets one.
+func (c config) baseURLs() map[string]string {
+	baseURLs := make(map[string]string)
+	for region, queues := range c.Regions {
+		if queues.BaseURL != "" {
+			baseURLs[region] = queues.BaseURL
+		}
+	}
+	return baseURLs
+}
+
+// regionNames returns the configured regions in sorted order.
+func (c config) regionNames() []string {
+	regions := make([]string, 0, len(c.Regions))
+	for region := range c.Regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// validateConfig reports every problem found in cfg, one error per problem.
+func validateConfig(cfg config) []error {
+	if len(cfg.Regions) == 0 {
+		return []error{errors.New("no regions configured")}
+	}
+
+	var problems []error
+	for _, region := range cfg.regionNames() {
+		problems = append(problems, validateRegion(region, cfg.Regions[region])...)
+	}
+	return problems
+}
+
+// validateRegion reports every problem found in the config of one region.
+func validateRegion(region string, queues cutoff.Queues) []error {
+	var problems []error
+	if strings.EqualFold(region, output.SchemaVersionKey) {
+		problems = append(problems, problemAt(fmt.Errorf("region %q: the name is reserved for the %s key of the output files", region, output.SchemaVersionKey), region))
+	} else if _, err := regionalRoute(region); err != nil {
+		problems = append(problems, problemAt(fmt.Errorf("region %q: %w", region, err), region))
+	}
+	if queues.BaseURL != "" {
+		if err := riot.ValidateBaseURL(queues.BaseURL); err != nil {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: invalid base_url: %w", region, err), region, "base_url"))
+		}
+	}
+	if queues.PollInterval != 0 && queues.PollInterval < minPollIntervalFloor {
+		problems = append(problems, problemAt(fmt.Errorf("region %q: poll_interval must be at least %s, got %s", region, minPollIntervalFloor, queues.PollInterval), region, pollIntervalKey))
+	}
+	if !queues.SoloDuo.IsEnabled() && !queues.Flex.IsEnabled() {
+		problems = append(problems, problemAt(fmt.Errorf("region %q: every queue is disabled", region), region))
+	}
+	for _, q := range []struct {
+		name    string
+		cutoffs cutoff.QueueConfig
+	}{
+		{"solo_duo", queues.SoloDuo},
+		{"flex", queues.Flex},
+	} {
+		if !q.cutoffs.IsEnabled() {
+			continue
+		}
+		if c := q.cutoffs.Cutoff; c != "" && c != cutoff.CutoffFloor && c != cutoff.CutoffRank {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: %s cutoff must be %q or %q, got %q", region, q.name, cutoff.CutoffFloor, cutoff.CutoffRank, c), region, q.name, "cutoff"))
+		}
+		for _, tier := range q.cutoffs.Tiers {
+			if tier != cutoff.TierChallenger && tier != cutoff.TierGrandmaster && tier != cutoff.TierMaster {
+				problems = append(problems, problemAt(fmt.Errorf("region %q: %s tiers has unknown tier %q, expected challenger, grandmaster or master", region, q.name, tier), region, q.name, "tiers"))
+			}
+		}
+		if len(q.cutoffs.Tiers) > 0 && !slices.Contains(q.cutoffs.Tiers, cutoff.TierChallenger) {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: %s tiers must include challenger", region, q.name), region, q.name, "tiers"))
+		}
+		if q.cutoffs.Challenger <= 0 {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: %s challenger slots must be positive, got %d", region, q.name, q.cutoffs.Challenger), region, q.name, "challenger"))
+		}
+		if q.cutoffs.Grandmaster <= 0 {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: %s grandmaster slots must be positive, got %d", region, q.name, q.cutoffs.Grandmaster), region, q.name, "grandmaster"))
+		}
+		for _, percentile := range []struct {
+			key   string
+			value float64
+		}{
+			{"challenger_percentile", q.cutoffs.ChallengerPercentile},
+			{"grandmaster_percentile", q.cutoffs.GrandmasterPercentile},
+		} {
+			if percentile.value < 0 || percentile.value > 100 {
+				problems = append(problems, problemAt(fmt.Errorf("region %q: %s %s must be between 0 and 100, got %g", region, q.name, percentile.key, percentile.value), region, q.name, percentile.key))
+			}
+		}
+		if total := q.cutoffs.ChallengerPercentile + q.cutoffs.GrandmasterPercentile; total > 100 {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: %s challenger_percentile and grandmaster_percentile must add up to at most 100, got %g", region, q.name, total), region, q.name))
+		}
+		challengerFloor, grandmasterFloor := q.cutoffs.ChallengerFloor(), q.cutoffs.GrandmasterFloor()
+		if grandmasterFloor < 0 {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: %s min_grandmaster_lp must not be negative, got %d", region, q.name, grandmasterFloor), region, q.name, "min_grandmaster_lp"))
+		}
+		if challengerFloor < grandmasterFloor {
+			problems = append(problems, problemAt(fmt.Errorf("region %q: %s min_challenger_lp (%d) must be at least min_grandmaster_lp (%d)", region, q.name, challengerFloor, grandmasterFloor), region, q.name))
+		}
+	}
+	return problems
+}
+
+// configWatcher reloads the config file between cycles whenever its
+// modification time changes or a reload was requested, e.g. on SIGHUP. A file
+// that fails to load leaves the previous config in place.
+type configWatcher struct {
+	path    string
+	regions []string
+	strict  bool
+	modTime time.Time
+	cfg     config
+	// reload is set by requestReload and cleared by current.
+	reload atomic.Bool
+}
+
+// newConfigWatcher loads the config from path, see loadConfig for strict.
+// When regions is non-empty only those regions are kept, on every reload.
+func newConfigWatcher(path string, regions []string, strict bool) (*configWatcher, error) {
+	w := &configWatcher{path: path, regions: regions, strict: strict}
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("stat config file %s: %w", path, err)
+		}
+		w.modTime = info.ModTime()
+	}
+
+	cfg, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.cfg = cfg
+	return w, nil
+}
+
+func (w *configWatcher) load() (config, error) {
+	cfg, err := loadConfig(w.path, w.strict)
+	if err != nil {
+		return config{}, err
+	}
+	return filterRegions(cfg, w.regions)
+}
+
+// requestReload makes the next call to current reload the config file even if
+// it didn't change on disk. It is safe to call from any goroutine.
+func (w *configWatcher) requestReload() {
+	w.reload.Store(true)
+}
+
+// current returns the config to use for the next cycle, reloading it first if
+// the file changed on disk or a reload was requested.
+func (w *configWatcher) current() config {
+	requested := w.reload.Swap(false)
+	if w.path == "" {
+		if requested {
+			slog.Info("No config file to reload, keeping the embedded cutoffs.yaml")
+		}
+		return w.cfg
+	}
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		slog.Error("Checking config file failed, keeping previous config", "path", w.path, "error", err)
+		return w.cfg
+	}
+	if !requested && info.ModTime().Equal(w.modTime) {
+		return w.cfg
+	}
+
+	cfg, err := w.load()
+	if err != nil {
+		slog.Error("Reloading config failed, keeping previous config", "path", w.path, "error", err)
+		return w.cfg
+	}
+	changes := configChanges(w.cfg, cfg)
+	w.modTime = info.ModTime()
+	w.cfg = cfg
+	slog.Info("Reloaded config", "path", w.path, "regions", len(cfg.Regions), "changes", len(changes))
+	for _, change := range changes {
+		slog.Info("Config changed", "change", change)
+	}
+	return w.cfg
+}
+
+// configChanges describes every difference between two configs, one line per
+// added or removed region and per changed setting.
+func configChanges(previous, current config) []string {
+	var changes []string
+	if previous.PollInterval != current.PollInterval {
+		changes = append(changes, fmt.Sprintf("%s: %s -> %s", pollIntervalKey,
+			describeInterval(previous.PollInterval), describeInterval(current.PollInterval)))
+	}
+
+	regions := slices.Concat(previous.regionNames(), current.regionNames())
+	slices.Sort(regions)
+	for _, region := range slices.Compact(regions) {
+		previousQueues, inPrevious := previous.Regions[region]
+		currentQueues, inCurrent := current.Regions[region]
+		switch {
+		case !inPrevious:
+			changes = append(changes, fmt.Sprintf("added region %s", region))
+			continue
+		case !inCurrent:
+			changes = append(changes, fmt.Sprintf("removed region %s", region))
+			continue
+		}
+
+		previousSettings, currentSettings := regionSettings(previousQueues), regionSettings(currentQueues)
+		keys := slices.Concat(slices.Collect(maps.Keys(previousSettings)), slices.Collect(maps.Keys(currentSettings)))
+		slices.Sort(keys)
+		for _, key := range slices.Compact(keys) {
+			before, hadBefore := previousSettings[key]
+			after, hasAfter := currentSettings[key]
+			if before == after && hadBefore == hasAfter {
+				continue
+			}
+			if !hadBefore {
+				before = "unset"
+			}
+			if !hasAfter {
+				after = "unset"
+			}
+			changes = append(changes, fmt.Sprintf("region %s: %s: %s -> %s", region, key, before, after))
+		}
+	}
+	return changes
+}
+
+func describeInterval(interval time.Duration) string {
+	if interval == 0 {
+		return "unset"
+	}
+	return interval.String()
+}
+
+// regionSettings flattens a region's config into its settings keyed by their
+// dotted YAML path, e.g. "solo_duo.challenger".
+func regionSettings(queues cutoff.Queues) map[string]string {
+	settings := make(map[string]string)
+	// Queues holds plain values only, so it always round-trips.
+	data, _ := yaml.Marshal(queues)
+	var doc yaml.MapSlice
+	yaml.Unmarshal(data, &doc)
+	flattenSettings(settings, "", doc)
+	return settings
+}
+
+func flattenSettings(into map[string]string, prefix string, doc yaml.MapSlice) {
+	for _, item := range doc {
+		key := prefix + fmt.Sprint(item.Key)
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			flattenSettings(into, key+".", nested)
+			continue
+		}
+		into[key] = fmt.Sprint(item.Value)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// regionYAML is a valid config block for region.
+func regionYAML(region string) string {
+	return region + ":\n" +
+		"    solo_duo:\n        challenger: 300\n        grandmaster: 700\n" +
+		"    flex:\n        challenger: 50\n        grandmaster: 100\n"
+}
+
+// writeConfig writes a config file into a temp dir and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cutoffs.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// replaceConfig overwrites the config file at path and moves its modification
+// time forward, so the change is seen even on coarse-grained file systems.
+func replaceConfig(t *testing.T, path, content string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigWatcherReloadsChangedFile(t *testing.T) {
+	path := writeConfig(t, regionYAML("euw1"))
+	w, err := newConfigWatcher(path, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.current().regionNames(); !slices.Equal(got, []string{"euw1"}) {
+		t.Fatalf("regions = %v, want [euw1]", got)
+	}
+
+	replaceConfig(t, path, regionYAML("euw1")+regionYAML("kr"))
+	if got := w.current().regionNames(); !slices.Equal(got, []string{"euw1", "kr"}) {
+		t.Errorf("regions after adding kr = %v, want [euw1 kr]", got)
+	}
+
+	replaceConfig(t, path, regionYAML("kr"))
+	if got := w.current().regionNames(); !slices.Equal(got, []string{"kr"}) {
+		t.Errorf("regions after removing euw1 = %v, want [kr]", got)
+	}
+}
+
+func TestConfigWatcherKeepsConfigOnBadReload(t *testing.T) {
+	path := writeConfig(t, regionYAML("euw1"))
+	w, err := newConfigWatcher(path, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replaceConfig(t, path, "euw1: [not, a, region")
+	if got := w.current().regionNames(); !slices.Equal(got, []string{"euw1"}) {
+		t.Errorf("regions after a bad reload = %v, want the previous [euw1]", got)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := cutoff.Queues{
+		SoloDuo: cutoff.QueueConfig{Challenger: 300, Grandmaster: 700},
+		Flex:    cutoff.QueueConfig{Challenger: 50, Grandmaster: 100},
+	}
+	with := func(change func(q *cutoff.Queues)) cutoff.Queues {
+		q := valid
+		change(&q)
+		return q
+	}
+
+	tests := []struct {
+		name    string
+		regions map[string]cutoff.Queues
+		want    []string
+	}{
+		{"valid", map[string]cutoff.Queues{"euw1": valid, "kr": valid}, nil},
+		{"no regions", map[string]cutoff.Queues{}, []string{"no regions configured"}},
+		{"unknown region", map[string]cutoff.Queues{"xx9": valid}, []string{`region "xx9": unknown platform`}},
+		{
+			"zero challenger slots",
+			map[string]cutoff.Queues{"euw1": with(func(q *cutoff.Queues) { q.SoloDuo.Challenger = 0 })},
+			[]string{`region "euw1": solo_duo challenger slots must be positive, got 0`},
+		},
+		{
+			"negative grandmaster slots",
+			map[string]cutoff.Queues{"euw1": with(func(q *cutoff.Queues) { q.Flex.Grandmaster = -5 })},
+			[]string{`region "euw1": flex grandmaster slots must be positive, got -5`},
+		},
+		{
+			"every problem is reported",
+			map[string]cutoff.Queues{
+				"euw1": with(func(q *cutoff.Queues) { q.SoloDuo.Challenger = 0; q.Flex.Grandmaster = 0 }),
+				"xx9":  valid,
+			},
+			[]string{
+				`region "euw1": solo_duo challenger slots must be positive, got 0`,
+				`region "euw1": flex grandmaster slots must be positive, got 0`,
+				`region "xx9": unknown platform`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := validateConfig(config{Regions: tt.regions})
+			if len(problems) != len(tt.want) {
+				t.Fatalf("got %d problems %v, want %d", len(problems), problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(problems[i].Error(), want) {
+					t.Errorf("problem %d = %q, want prefix %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigFloors(t *testing.T) {
+	path := writeConfig(t, `euw1:
+    min_challenger_lp: 700
+    min_grandmaster_lp: 300
+    solo_duo:
+        challenger: 300
+        grandmaster: 700
+    flex:
+        challenger: 50
+        grandmaster: 100
+        min_grandmaster_lp: 100
+`+regionYAML("kr"))
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                            string
+		queue                           cutoff.QueueConfig
+		wantChallenger, wantGrandmaster int
+	}{
+		{"region floors", cfg.Regions["euw1"].SoloDuo, 700, 300},
+		{"queue floor over the region's", cfg.Regions["euw1"].Flex, 700, 100},
+		{"default floors", cfg.Regions["kr"].SoloDuo, cutoff.DefaultMinChallengerLP, cutoff.DefaultMinGrandmasterLP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.queue.ChallengerFloor(); got != tt.wantChallenger {
+				t.Errorf("Challenger floor = %d, want %d", got, tt.wantChallenger)
+			}
+			if got := tt.queue.GrandmasterFloor(); got != tt.wantGrandmaster {
+				t.Errorf("Grandmaster floor = %d, want %d", got, tt.wantGrandmaster)
+			}
+			// An empty ladder yields the floors.
+			if got := cutoff.CalculateCutoffs(nil, tt.queue); got.Challenger != tt.wantChallenger || got.Grandmaster != tt.wantGrandmaster {
+				t.Errorf("empty ladder cutoffs = %d/%d, want the floors %d/%d", got.Challenger, got.Grandmaster, tt.wantChallenger, tt.wantGrandmaster)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsInvertedFloors(t *testing.T) {
+	path := writeConfig(t, "euw1:\n    min_challenger_lp: 100\n    min_grandmaster_lp: 300\n"+strings.TrimPrefix(regionYAML("euw1"), "euw1:\n"))
+	_, err := loadConfig(path, true)
+	if err == nil || !strings.Contains(err.Error(), "min_challenger_lp (100) must be at least min_grandmaster_lp (300)") {
+		t.Errorf("err = %v, want the inverted floors reported", err)
+	}
+}
+
+func TestLoadConfigDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			"region",
+			regionYAML("euw1") + regionYAML("kr") + regionYAML("euw1"),
+			`line 15: region "euw1" is defined more than once, first at line 1`,
+		},
+		{
+			"queue field",
+			"euw1:\n    solo_duo:\n        challenger: 300\n        challenger: 200\n        grandmaster: 700\n" +
+				"    flex:\n        challenger: 50\n        grandmaster: 100\n",
+			`line 4: region "euw1": solo_duo.challenger is defined more than once, first at line 3`,
+		},
+		{
+			"poll_interval",
+			"poll_interval: 1m\npoll_interval: 2m\n" + regionYAML("euw1"),
+			"line 2: poll_interval is defined more than once, first at line 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(writeConfig(t, tt.content), true)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigBaseURLs(t *testing.T) {
+	cfg, err := loadConfig(writeConfig(t, "kr:\n    base_url: http://127.0.0.1:9000\n"+strings.TrimPrefix(regionYAML("kr"), "kr:\n")+regionYAML("euw1")), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.baseURLs(), map[string]string{"kr": "http://127.0.0.1:9000"}; !maps.Equal(got, want) {
+		t.Errorf("base URLs = %v, want %v", got, want)
+	}
+
+	_, err = loadConfig(writeConfig(t, "kr:\n    base_url: ftp://gateway\n"+strings.TrimPrefix(regionYAML("kr"), "kr:\n")), true)
+	if err == nil || !strings.Contains(err.Error(), `line 2: region "kr": invalid base_url`) {
+		t.Errorf("err = %v, want the invalid base_url reported at line 2", err)
+	}
+}
+
+func TestLoadConfigCutoffSemantics(t *testing.T) {
+	cfg, err := loadConfig(writeConfig(t, "euw1:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n"+
+		"    flex:\n        challenger: 50\n        grandmaster: 100\n        cutoff: rank\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Regions["euw1"].SoloDuo.Cutoff; got != "" {
+		t.Errorf("solo_duo cutoff = %q, want the default", got)
+	}
+	if got := cfg.Regions["euw1"].Flex.Cutoff; got != cutoff.CutoffRank {
+		t.Errorf("flex cutoff = %q, want %q", got, cutoff.CutoffRank)
+	}
+
+	_, err = loadConfig(writeConfig(t, "euw1:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n"+
+		"    flex:\n        challenger: 50\n        grandmaster: 100\n        cutoff: count\n"), true)
+	if err == nil || !strings.Contains(err.Error(), `line 8: region "euw1": flex cutoff must be "floor" or "rank", got "count"`) {
+		t.Errorf("err = %v, want the unknown cutoff reported at line 8", err)
+	}
+}
+
+func TestLoadConfigRejectsSchemaVersionRegion(t *testing.T) {
+	_, err := loadConfig(writeConfig(t, regionYAML("euw1")+regionYAML("schemaVersion")), true)
+	want := `line 8: region "schemaversion": the name is reserved for the schemaVersion key of the output files`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
+
+func TestLoadConfigSkipsBadRegions(t *testing.T) {
+	content := regionYAML("euw1") +
+		"kr:\n    solo_duo:\n        challenger: lots\n        grandmaster: 700\n    flex:\n        challenger: 50\n        grandmaster: 100\n" +
+		"na1:\n    solo_duo:\n        challenger: 0\n        grandmaster: 700\n    flex:\n        challenger: 50\n        grandmaster: 100\n" +
+		"br1:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n" +
+		regionYAML("jp1")
+
+	t.Run("skip bad", func(t *testing.T) {
+		cfg, err := loadConfig(writeConfig(t, content), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.regionNames(); !slices.Equal(got, []string{"euw1", "jp1"}) {
+			t.Errorf("regions = %v, want [euw1 jp1]", got)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := loadConfig(writeConfig(t, content), true)
+		if err == nil {
+			t.Fatal("loadConfig succeeded, want the bad regions reported")
+		}
+		for _, want := range []string{"line 10: region \"kr\": cannot unmarshal !!str `lots` into int", `line 17: region "na1": solo_duo challenger slots must be positive`, `line 22: region "br1": missing the flex queue`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("err = %v, want %q", err, want)
+			}
+		}
+	})
+
+	t.Run("no valid region", func(t *testing.T) {
+		if _, err := loadConfig(writeConfig(t, "br1:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n"), false); err == nil {
+			t.Error("loadConfig succeeded, want an error when every region is bad")
+		}
+	})
+
+	t.Run("unparseable", func(t *testing.T) {
+		if _, err := loadConfig(writeConfig(t, "euw1: [not, a, region"), false); err == nil {
+			t.Error("loadConfig succeeded, want an error for a document that doesn't parse")
+		}
+	})
+}
+
+func TestLoadConfigTiers(t *testing.T) {
+	queues := func(tiers string) string {
+		return "euw1:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n        tiers: " + tiers + "\n" +
+			"    flex:\n        challenger: 50\n        grandmaster: 100\n"
+	}
+	cfg, err := loadConfig(writeConfig(t, queues("[challenger, grandmaster]")), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Regions["euw1"].SoloDuo.Leagues(); !slices.Equal(got, []string{cutoff.LeagueChallenger, cutoff.LeagueGrandmaster}) {
+		t.Errorf("solo_duo leagues = %v, want Challenger and Grandmaster", got)
+	}
+	if got := cfg.Regions["euw1"].Flex.Leagues(); len(got) != 3 {
+		t.Errorf("flex leagues = %v, want all three", got)
+	}
+
+	for tiers, want := range map[string]string{
+		"[challenger, diamond]": `line 5: region "euw1": solo_duo tiers has unknown tier "diamond"`,
+		"[grandmaster, master]": `line 5: region "euw1": solo_duo tiers must include challenger`,
+	} {
+		if _, err := loadConfig(writeConfig(t, queues(tiers)), true); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("tiers %s: err = %v, want %q", tiers, err, want)
+		}
+	}
+}
+
+func TestLoadConfigPercentiles(t *testing.T) {
+	queues := func(percentiles string) string {
+		return "euw1:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n" +
+			"    flex:\n        challenger: 50\n        grandmaster: 100\n" + percentiles
+	}
+	cfg, err := loadConfig(writeConfig(t, queues("        challenger_percentile: 0.5\n        interpolate: true\n")), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flex := cfg.Regions["euw1"].Flex; flex.ChallengerPercentile != 0.5 || !flex.Interpolate {
+		t.Errorf("flex = %+v, want a 0.5 Challenger percentile, interpolated", flex)
+	}
+
+	for percentiles, want := range map[string]string{
+		"        challenger_percentile: 101\n":                                    `line 8: region "euw1": flex challenger_percentile must be between 0 and 100, got 101`,
+		"        grandmaster_percentile: -1\n":                                    `line 8: region "euw1": flex grandmaster_percentile must be between 0 and 100, got -1`,
+		"        challenger_percentile: 40\n        grandmaster_percentile: 70\n": `line 5: region "euw1": flex challenger_percentile and grandmaster_percentile must add up to at most 100, got 110`,
+	} {
+		if _, err := loadConfig(writeConfig(t, queues(percentiles)), true); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want %q", percentiles, err, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config values can be overridden through LPCUTOFF_* environment variables, so
+// a container doesn't need a mounted config file for small tweaks. The name
+// after the prefix is the path of the value in the config, with keys
+// separated by a double underscore since they contain single ones:
+//
+//	LPCUTOFF_POLL_INTERVAL=2m
+//	LPCUTOFF_EUW1__SOLO_DUO__CHALLENGER=300
+//	LPCUTOFF_KR__FLEX__ENABLED=false
+//
+// Values are parsed as YAML, so lists such as "[challenger, grandmaster]"
+// work too. The variables listed in settingEnvAliases are aliases of settings
+// rather than config values.
+const configEnvPrefix = "LPCUTOFF_"
+
+// settingEnvAliases maps the LPCUTOFF_* variables that stand for a setting,
+// not a config value, to the variable of the setting. An alias takes
+// precedence over the setting's own variable.
+var settingEnvAliases = map[string]string{
+	"LPCUTOFF_OUTPUT_DIR": "OUTPUT_DIR",
+}
+
+// settingEnv returns the value of the setting variable name, or of its
+// LPCUTOFF_* alias when that is set.
+func settingEnv(name string) string {
+	for alias, setting := range settingEnvAliases {
+		if setting != name {
+			continue
+		}
+		if value := os.Getenv(alias); value != "" {
+			return value
+		}
+	}
+	return os.Getenv(name)
+}
+
+// applyEnvOverrides sets the config values overridden by the LPCUTOFF_*
+// variables of environ in doc. It returns the overridden paths, dot-separated
+// with the region as its platform code, mapped to their variable, and an error
+// for each variable that can't be applied.
+func applyEnvOverrides(doc yaml.MapSlice, environ []string) (yaml.MapSlice, map[string]string, []error) {
+	overrides := make(map[string]string)
+	var problems []error
+	environ = slices.Sorted(slices.Values(environ))
+	for _, entry := range environ {
+		name, raw, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, configEnvPrefix) || raw == "" {
+			continue
+		}
+		if _, ok := settingEnvAliases[name]; ok {
+			continue
+		}
+		path := strings.Split(strings.ToLower(strings.TrimPrefix(name, configEnvPrefix)), "__")
+		if slices.Contains(path, "") || (len(path) == 1 && path[0] != pollIntervalKey) {
+			problems = append(problems, fmt.Errorf("%s: unknown config value, expected %sPOLL_INTERVAL or %s<REGION>__<KEY>, e.g. %sEUW1__SOLO_DUO__CHALLENGER",
+				name, configEnvPrefix, configEnvPrefix, configEnvPrefix))
+			continue
+		}
+		var value any
+		if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+			problems = append(problems, fmt.Errorf("%s: invalid value %q: %w", name, raw, err))
+			continue
+		}
+
+		doc = setConfigValue(doc, path, value, true)
+		if len(path) > 1 {
+			path[0] = normalizePlatform(path[0])
+		}
+		overrides[strings.Join(path, ".")] = name
+		slog.Debug("Overriding config value from the environment", "variable", name, "value", raw)
+	}
+	return doc, overrides, problems
+}
+
+// setConfigValue sets the value at path in doc, adding the keys that are
+// missing. At the top level, a region key matches any of its aliases.
+func setConfigValue(doc yaml.MapSlice, path []string, value any, top bool) yaml.MapSlice {
+	for i, item := range doc {
+		key := fmt.Sprint(item.Key)
+		if key != path[0] && (!top || normalizePlatform(key) != normalizePlatform(path[0])) {
+			continue
+		}
+		if len(path) == 1 {
+			doc[i].Value = value
+		} else {
+			nested, _ := item.Value.(yaml.MapSlice)
+			doc[i].Value = setConfigValue(nested, path[1:], value, false)
+		}
+		return doc
+	}
+
+	if len(path) == 1 {
+		return append(doc, yaml.MapItem{Key: path[0], Value: value})
+	}
+	return append(doc, yaml.MapItem{Key: path[0], Value: setConfigValue(nil, path[1:], value, false)})
+}
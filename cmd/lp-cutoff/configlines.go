@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// The config is decoded with gopkg.in/yaml.v2, which doesn't keep positions.
+// To report problems with their line, the document is parsed a second time
+// into a yaml.v3 node tree that is only used to locate keys.
+
+// configProblem is a problem with the value at path in the config document,
+// e.g. ["euw1", "solo_duo", "challenger"].
+type configProblem struct {
+	path []string
+	err  error
+}
+
+func (p *configProblem) Error() string { return p.err.Error() }
+func (p *configProblem) Unwrap() error { return p.err }
+
+// problemAt attaches the path of the value err is about, so locateProblems can
+// report its line.
+func problemAt(err error, path ...string) error {
+	return &configProblem{path: path, err: err}
+}
+
+// parseConfigNodes parses data into a yaml.v3 node tree, returning nil when it
+// doesn't parse; problems are then reported without lines.
+func parseConfigNodes(data []byte) *yamlv3.Node {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return nil
+	}
+	return root.Content[0]
+}
+
+// locateProblems prefixes every configProblem with the line of its path, or of
+// the closest ancestor of the path found in the document. Problems with a value
+// overridden from the environment, see applyEnvOverrides, name the variable
+// instead.
+func locateProblems(root *yamlv3.Node, overrides map[string]string, problems []error) []error {
+	located := make([]error, len(problems))
+	for i, err := range problems {
+		located[i] = err
+		var problem *configProblem
+		if !errors.As(err, &problem) {
+			continue
+		}
+		if name, ok := overrides[strings.Join(problem.path, ".")]; ok {
+			located[i] = fmt.Errorf("%s: %w", name, err)
+		} else if line := lineOf(root, problem.path); line > 0 {
+			located[i] = fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return located
+}
+
+// lineOf returns the line of the deepest key along path, zero when not even
+// the first is found. The first key also matches region aliases, as the path
+// of a region holds its platform code.
+func lineOf(root *yamlv3.Node, path []string) int {
+	line := 0
+	node := root
+	for depth, key := range path {
+		value := mappingValue(node, func(k string) bool { return k == key })
+		if value == nil && depth == 0 {
+			value = mappingValue(node, func(k string) bool { return normalizePlatform(k) == key })
+		}
+		if value == nil {
+			break
+		}
+		line, node = value[0].Line, value[1]
+	}
+	return line
+}
+
+// mappingValue returns the first key node of a mapping matching match, along
+// with its value node, or nil.
+func mappingValue(node *yamlv3.Node, match func(key string) bool) []*yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if match(node.Content[i].Value) {
+			return node.Content[i : i+2]
+		}
+	}
+	return nil
+}
+
+// typeProblems splits an error decoding region with yaml.v3 into one error
+// per value that has the wrong type. Values overridden by the variable name
+// are reported with the variable, values from the file with their line.
+func typeProblems(err error, region, name string) []error {
+	if err == nil {
+		return nil
+	}
+	var typeErr *yamlv3.TypeError
+	if !errors.As(err, &typeErr) {
+		return []error{fmt.Errorf("%s: region %q: %w", cmp.Or(name, "config"), region, err)}
+	}
+	problems := make([]error, len(typeErr.Errors))
+	for i, message := range typeErr.Errors {
+		at, problem, ok := strings.Cut(message, ": ")
+		if !ok || !strings.HasPrefix(at, "line ") {
+			at, problem = "config", message
+		}
+		problems[i] = fmt.Errorf("%s: region %q: %s", cmp.Or(name, at), region, problem)
+	}
+	return problems
+}
+
+// withoutKey returns a copy of the mapping node without the key at path,
+// sharing the nodes it leaves unchanged.
+func withoutKey(node *yamlv3.Node, path []string) *yamlv3.Node {
+	if node.Kind != yamlv3.MappingNode {
+		return node
+	}
+	copied := *node
+	copied.Content = make([]*yamlv3.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == path[0] {
+			if len(path) == 1 {
+				continue
+			}
+			value = withoutKey(value, path[1:])
+		}
+		copied.Content = append(copied.Content, key, value)
+	}
+	return &copied
+}
+
+// duplicateKeys reports every key defined more than once in the same mapping
+// of the config document, at any depth. Decoding would silently keep only the
+// last occurrence.
+func duplicateKeys(root *yamlv3.Node) []error {
+	var problems []error
+	var walk func(node *yamlv3.Node, path []string)
+	walk = func(node *yamlv3.Node, path []string) {
+		if node.Kind != yamlv3.MappingNode {
+			return
+		}
+		seen := make(map[string]int, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if first, ok := seen[key.Value]; ok {
+				var err error
+				switch {
+				case len(path) > 0:
+					field := strings.Join(append(path[1:len(path):len(path)], key.Value), ".")
+					err = fmt.Errorf("line %d: region %q: %s is defined more than once, first at line %d", key.Line, path[0], field, first)
+				case key.Value == pollIntervalKey:
+					err = fmt.Errorf("line %d: %s is defined more than once, first at line %d", key.Line, key.Value, first)
+				default:
+					err = fmt.Errorf("line %d: region %q is defined more than once, first at line %d", key.Line, key.Value, first)
+				}
+				problems = append(problems, err)
+				continue
+			}
+			seen[key.Value] = key.Line
+			walk(node.Content[i+1], append(path[:len(path):len(path)], key.Value))
+		}
+	}
+	if root != nil {
+		walk(root, nil)
+	}
+	return problems
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigReportsLines(t *testing.T) {
+	content := regionYAML("euw1") + // lines 1-7
+		"xx9:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n    flex:\n        challenger: 50\n        grandmaster: 100\n" + // 8-14
+		"kr:\n    solo_duo:\n        challenger: 0\n        grandmaster: 700\n    flex:\n        challenger: 50\n        grandmaster: -5\n" + // 15-21
+		"na:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n" + // 22-25
+		"jp1:\n    solo_duo:\n        challenger: 300\n        challenger: 200\n        grandmaster: 700\n    flex:\n        challenger: 50\n        grandmaster: 100\n" // 26-33
+	_, err := loadConfig(writeConfig(t, content), true)
+	if err == nil {
+		t.Fatal("loadConfig succeeded, want every problem reported")
+	}
+	for _, want := range []string{
+		`line 8: region "xx9": unknown platform`,
+		`line 17: region "kr": solo_duo challenger slots must be positive, got 0`,
+		`line 21: region "kr": flex grandmaster slots must be positive, got -5`,
+		// The alias na is reported as written.
+		`line 22: region "na": missing the flex queue`,
+		`line 29: region "jp1": solo_duo.challenger is defined more than once, first at line 28`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v\nwant it to contain %q", err, want)
+		}
+	}
+}
+
+func TestLoadConfigNamesOverriddenValues(t *testing.T) {
+	t.Setenv("LPCUTOFF_EUW1__SOLO_DUO__CHALLENGER", "0")
+	_, err := loadConfig(writeConfig(t, regionYAML("euw1")), true)
+	want := `LPCUTOFF_EUW1__SOLO_DUO__CHALLENGER: region "euw1": solo_duo challenger slots must be positive, got 0`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
+
+func TestLoadConfigReportsTypeErrors(t *testing.T) {
+	content := regionYAML("euw1") + // lines 1-7
+		"kr:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n    flex:\n        challenger: 50\n        grandmaster: abc\n" // 8-14
+	tests := []struct {
+		name string
+		env  []string
+		want []string
+	}{
+		{"nested value", nil, []string{"line 14: region \"kr\": cannot unmarshal !!str `abc` into int"}},
+		{"overridden value", []string{"LPCUTOFF_EUW1__SOLO_DUO__CHALLENGER", "abc"}, []string{
+			"LPCUTOFF_EUW1__SOLO_DUO__CHALLENGER: region \"euw1\": cannot unmarshal !!str `abc` into int",
+			"line 14: region \"kr\"",
+		}},
+		{"overridden value of a region not in the file", []string{
+			"LPCUTOFF_BR1__SOLO_DUO", "{challenger: 300, grandmaster: 700}",
+			"LPCUTOFF_BR1__FLEX", "{challenger: 50, grandmaster: [1]}",
+		}, []string{
+			"LPCUTOFF_BR1__FLEX: region \"br1\": cannot unmarshal !!seq into int",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i+1 < len(tt.env); i += 2 {
+				t.Setenv(tt.env[i], tt.env[i+1])
+			}
+			_, err := loadConfig(writeConfig(t, content), true)
+			if err == nil {
+				t.Fatal("loadConfig succeeded, want the type errors reported")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %v\nwant it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigOverrideReplacesBadValue(t *testing.T) {
+	t.Setenv("LPCUTOFF_KR__FLEX__GRANDMASTER", "5")
+	cfg, err := loadConfig(writeConfig(t, "kr:\n    solo_duo:\n        challenger: 300\n        grandmaster: 700\n"+
+		"    flex:\n        challenger: 50\n        grandmaster: abc\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Regions["kr"].Flex; got.Challenger != 50 || got.Grandmaster != 5 {
+		t.Errorf("flex slots = %d/%d, want 50 from the file and 5 from the override", got.Challenger, got.Grandmaster)
+	}
+}
+
+func TestLineOf(t *testing.T) {
+	root := parseConfigNodes([]byte("poll_interval: 1m\neuw:\n    solo_duo:\n        challenger: 300\n    flex:\n        grandmaster: 100\n"))
+	tests := []struct {
+		path []string
+		want int
+	}{
+		{[]string{"poll_interval"}, 1},
+		{[]string{"euw1"}, 2},
+		{[]string{"euw1", "solo_duo", "challenger"}, 4},
+		{[]string{"euw1", "flex", "grandmaster"}, 6},
+		// A missing key falls back to its closest ancestor.
+		{[]string{"euw1", "flex", "challenger"}, 5},
+		{[]string{"kr", "flex"}, 0},
+	}
+	for _, tt := range tests {
+		if got := lineOf(root, tt.path); got != tt.want {
+			t.Errorf("lineOf(%v) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLocateProblemsWithoutNodes(t *testing.T) {
+	// A document that doesn't parse as YAML v3 still reports its problems,
+	// without lines.
+	problem := problemAt(errors.New(`region "euw1": flex challenger slots must be positive, got 0`), "euw1", "flex", "challenger")
+	located := locateProblems(parseConfigNodes([]byte("euw1: [")), nil, []error{problem})
+	if got := located[0].Error(); got != problem.Error() {
+		t.Errorf("problem = %q, want %q unchanged", got, problem.Error())
+	}
+	if !errors.Is(located[0], problem) {
+		t.Error("located problem doesn't wrap the original")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+// dashboardPage is the data the dashboard template renders.
+type dashboardPage struct {
+	UpdatedAt time.Time
+	Regions   []dashboardRegion
+}
+
+type dashboardRegion struct {
+	Region     string
+	Data       cutoff.RegionData
+	Solo, Flex cutoff.Cutoffs
+}
+
+// handleDashboard renders the current snapshot as an HTML table that reloads
+// itself every minute. Stale regions are the ones whose last cycle failed.
+func (srv *server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	data, updatedAt := srv.store.get()
+	page := dashboardPage{UpdatedAt: updatedAt}
+	for _, region := range slices.Sorted(maps.Keys(data)) {
+		d := data[region]
+		page.Regions = append(page.Regions, dashboardRegion{
+			Region: region,
+			Data:   d,
+			Solo:   d.RANKED_SOLO_5x5,
+			Flex:   d.RANKED_FLEX_SR,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := dashboardTemplate.Execute(&buf, page); err != nil {
+		slog.Error("Rendering dashboard failed", "error", err)
+		http.Error(w, "rendering dashboard failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(buf.Bytes())
+}
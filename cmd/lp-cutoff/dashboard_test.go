@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+func TestDashboard(t *testing.T) {
+	stale := regionData(1100, 600)
+	stale.Stale = true
+	provisional := regionData(1250, 750)
+	provisional.RANKED_FLEX_SR.Provisional = true
+	minimal := regionData(1300, 0)
+	minimal.RANKED_SOLO_5x5.ChallengerOnly = true
+	minimal.RANKED_FLEX_SR = cutoff.Cutoffs{}
+	srv := testServer(map[string]cutoff.RegionData{
+		"euw1": regionData(900, 400),
+		"kr":   stale,
+		"na1":  provisional,
+		"jp1":  minimal,
+	}, func(srv *server) { srv.dashboard = true })
+
+	w := serve(srv, http.MethodGet, "/", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want HTML", got)
+	}
+	// Drop the template's whitespace between tags.
+	body := regexp.MustCompile(`>\s+<`).ReplaceAllString(w.Body.String(), "><")
+	for _, want := range []string{
+		"<td>euw1</td><td>900</td><td>400</td>",
+		"<td>kr</td>",
+		`<td class="status stale">stale, last cycle failed</td>`,
+		`<td class="provisional" title="provisional">1250</td>`,
+		// jp1's solo/duo has no Grandmaster cutoff and its flex queue is off.
+		"<td>jp1</td><td>1300</td><td>–</td><td>–</td><td>–</td>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard doesn't contain %q:\n%s", want, body)
+		}
+	}
+	// Regions are listed alphabetically.
+	if euw1, jp1, kr, na1 := strings.Index(body, ">euw1<"), strings.Index(body, ">jp1<"), strings.Index(body, ">kr<"), strings.Index(body, ">na1<"); !(euw1 < jp1 && jp1 < kr && kr < na1) {
+		t.Errorf("regions out of order at %d, %d, %d, %d", euw1, jp1, kr, na1)
+	}
+}
+
+func TestDashboardBeforeFirstCycle(t *testing.T) {
+	srv := testServer(nil, func(srv *server) { srv.dashboard = true })
+	w := serve(srv, http.MethodGet, "/", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Cutoffs not computed yet.") {
+		t.Errorf("response = %d %q, want the not computed notice", w.Code, w.Body)
+	}
+}
+
+func TestDashboardDisabled(t *testing.T) {
+	srv := testServer(map[string]cutoff.RegionData{"euw1": regionData(900, 400)})
+	if w := serve(srv, http.MethodGet, "/", nil); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 without the dashboard", w.Code)
+	}
+}
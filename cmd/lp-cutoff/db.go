@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// cutoffsSchema is the schema of the optional SQLite history. Each cycle
+// inserts one row per region, queue and tier; recorded_at is an RFC 3339 UTC
+// timestamp so rows sort chronologically and group by date with
+// substr(recorded_at, 1, 10).
+const cutoffsSchema = `
+CREATE TABLE IF NOT EXISTS cutoffs (
+	recorded_at TEXT    NOT NULL,
+	region      TEXT    NOT NULL,
+	queue       TEXT    NOT NULL,
+	tier        TEXT    NOT NULL,
+	cutoff_lp   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS cutoffs_region_recorded_at ON cutoffs (region, recorded_at);
+`
+
+// cutoffDB records every cycle's cutoffs in SQLite for historical queries.
+type cutoffDB struct {
+	db *sql.DB
+}
+
+func openCutoffDB(path string) (*cutoffDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open database %s: %w", path, err)
+	}
+	// SQLite allows a single writer; serializing through one connection
+	// avoids SQLITE_BUSY errors.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(cutoffsSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create schema in %s: %w", path, err)
+	}
+	return &cutoffDB{db: db}, nil
+}
+
+// record inserts the cutoffs of every freshly updated region in outputData.
+// Stale regions are skipped since their values were already recorded.
+func (d *cutoffDB) record(ctx context.Context, recordedAt time.Time, outputData map[string]cutoff.RegionData) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO cutoffs (recorded_at, region, queue, tier, cutoff_lp) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	at := recordedAt.UTC().Format(time.RFC3339)
+	for region, data := range outputData {
+		if data.Stale {
+			continue
+		}
+		for _, value := range data.Values() {
+			if _, err := stmt.ExecContext(ctx, at, region, value.Queue, value.Tier, value.LP); err != nil {
+				return fmt.Errorf("insert %s %s %s: %w", region, value.Queue, value.Tier, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func (d *cutoffDB) Close() error {
+	return d.db.Close()
+}
+
+// extremesSince returns the lowest and highest value every cutoff took in
+// the rows recorded at or after since.
+func (d *cutoffDB) extremesSince(ctx context.Context, since time.Time) ([]cutoffExtreme, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT region, queue, tier, MIN(cutoff_lp), MAX(cutoff_lp) FROM cutoffs
+		WHERE recorded_at >= ? GROUP BY region, queue, tier`, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("query extremes: %w", err)
+	}
+	defer rows.Close()
+
+	var extremes []cutoffExtreme
+	for rows.Next() {
+		var e cutoffExtreme
+		if err := rows.Scan(&e.Region, &e.Queue, &e.Tier, &e.Min, &e.Max); err != nil {
+			return nil, fmt.Errorf("scan extremes: %w", err)
+		}
+		extremes = append(extremes, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read extremes: %w", err)
+	}
+	return extremes, nil
+}
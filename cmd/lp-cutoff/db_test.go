@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// testDB opens a cutoff database in a temp dir.
+func testDB(t *testing.T) *cutoffDB {
+	t.Helper()
+	db, err := openCutoffDB(filepath.Join(t.TempDir(), "cutoffs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestCutoffDBRecordsCycles(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	start := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+
+	stale := regionData(1, 1)
+	stale.Stale = true
+	cycles := []map[string]cutoff.RegionData{
+		{"euw1": regionData(900, 400), "kr": regionData(1100, 600)},
+		{"euw1": regionData(910, 410), "kr": stale},
+		{"euw1": regionData(920, 420), "kr": regionData(1150, 650)},
+	}
+	for i, outputData := range cycles {
+		if err := db.record(ctx, start.Add(time.Duration(i)*time.Minute), outputData); err != nil {
+			t.Fatalf("record cycle %d: %v", i, err)
+		}
+	}
+
+	rows, err := db.db.QueryContext(ctx, `SELECT recorded_at, cutoff_lp FROM cutoffs
+		WHERE region = ? AND queue = ? AND tier = ? ORDER BY recorded_at`, "kr", cutoff.QueueSoloDuo, cutoff.TierChallenger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var recordedAt []string
+	var lps []int
+	for rows.Next() {
+		var at string
+		var lp int
+		if err := rows.Scan(&at, &lp); err != nil {
+			t.Fatal(err)
+		}
+		recordedAt, lps = append(recordedAt, at), append(lps, lp)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2024-03-31T12:00:00Z", "2024-03-31T12:02:00Z"}; !slices.Equal(recordedAt, want) {
+		t.Errorf("kr recorded at %v, want %v without the stale cycle", recordedAt, want)
+	}
+	if want := []int{1100, 1150}; !slices.Equal(lps, want) {
+		t.Errorf("kr cutoffs = %v, want %v", lps, want)
+	}
+
+	var total int
+	if err := db.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM cutoffs`).Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	// Four values (two queues, two tiers) per fresh region and cycle.
+	if total != 4*5 {
+		t.Errorf("%d rows, want %d", total, 4*5)
+	}
+}
+
+func TestOpenCutoffDBKeepsExistingRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cutoffs.db")
+	db, err := openCutoffDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.record(context.Background(), time.Now(), map[string]cutoff.RegionData{"euw1": regionData(900, 400)}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if db, err = openCutoffDB(path); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	var total int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM cutoffs`).Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 {
+		t.Errorf("%d rows after reopening, want 4", total)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// cutoffExtreme is the lowest and highest value a cutoff took during the
+// current archive period.
+type cutoffExtreme struct {
+	Region string `json:"region"`
+	Queue  string `json:"queue"`
+	Tier   string `json:"tier"`
+	Min    int    `json:"min"`
+	Max    int    `json:"max"`
+}
+
+// dailyExtremes is the content of current/daily-extremes.json.
+type dailyExtremes struct {
+	Period   string          `json:"period"`
+	Extremes []cutoffExtreme `json:"extremes"`
+}
+
+type extremeKey struct {
+	Region, Queue, Tier string
+}
+
+// extremesTracker keeps the low and high watermark of every cutoff within one
+// archive period.
+type extremesTracker struct {
+	period string
+	values map[extremeKey]cutoffExtreme
+}
+
+// reset starts tracking a new period, dropping every watermark.
+func (t *extremesTracker) reset(period string) {
+	t.period = period
+	t.values = make(map[extremeKey]cutoffExtreme)
+}
+
+// update widens the watermark of the extreme's cutoff to include its range.
+func (t *extremesTracker) update(e cutoffExtreme) {
+	key := extremeKey{e.Region, e.Queue, e.Tier}
+	if current, ok := t.values[key]; ok {
+		e.Min = min(e.Min, current.Min)
+		e.Max = max(e.Max, current.Max)
+	}
+	t.values[key] = e
+}
+
+// observe updates the watermarks with every freshly computed cutoff in
+// outputData. Stale regions repeat values already observed and are skipped.
+func (t *extremesTracker) observe(outputData map[string]cutoff.RegionData) {
+	for region, data := range outputData {
+		if data.Stale {
+			continue
+		}
+		for _, value := range data.Values() {
+			t.update(cutoffExtreme{Region: region, Queue: value.Queue, Tier: value.Tier, Min: value.LP, Max: value.LP})
+		}
+	}
+}
+
+// snapshot returns the watermarks sorted by region, queue and tier.
+func (t *extremesTracker) snapshot() dailyExtremes {
+	extremes := make([]cutoffExtreme, 0, len(t.values))
+	for _, e := range t.values {
+		extremes = append(extremes, e)
+	}
+	sort.Slice(extremes, func(i, j int) bool {
+		a, b := extremes[i], extremes[j]
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.Queue != b.Queue {
+			return a.Queue < b.Queue
+		}
+		return a.Tier < b.Tier
+	})
+	return dailyExtremes{Period: t.period, Extremes: extremes}
+}
+
+// writeExtremesFile writes the watermarks to current/daily-extremes.json.
+func writeExtremesFile(outputDir string, extremes dailyExtremes) error {
+	jsonData, err := json.MarshalIndent(extremes, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshal daily extremes JSON: %w", err)
+	}
+
+	currentDir := filepath.Join(outputDir, "current")
+	if err := output.EnsureDir(currentDir); err != nil {
+		return err
+	}
+	return output.WriteFile(filepath.Join(currentDir, "daily-extremes.json"), jsonData)
+}
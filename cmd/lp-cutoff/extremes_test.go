@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// soloChallenger returns the solo/duo Challenger watermark of region.
+func soloChallenger(extremes dailyExtremes, region string) (cutoffExtreme, bool) {
+	i := slices.IndexFunc(extremes.Extremes, func(e cutoffExtreme) bool {
+		return e.Region == region && e.Queue == cutoff.QueueSoloDuo && e.Tier == cutoff.TierChallenger
+	})
+	if i < 0 {
+		return cutoffExtreme{}, false
+	}
+	return extremes.Extremes[i], true
+}
+
+func TestExtremesTracker(t *testing.T) {
+	stale := regionData(100, 50)
+	stale.Stale = true
+
+	var tracker extremesTracker
+	tracker.reset("2024-03-31")
+	for _, challenger := range []int{745, 712, 730} {
+		tracker.observe(map[string]cutoff.RegionData{"euw1": regionData(challenger, 400), "kr": stale})
+	}
+
+	extremes := tracker.snapshot()
+	if extremes.Period != "2024-03-31" {
+		t.Errorf("period = %q, want 2024-03-31", extremes.Period)
+	}
+	if got, _ := soloChallenger(extremes, "euw1"); got.Min != 712 || got.Max != 745 {
+		t.Errorf("euw1 Challenger range = %d-%d, want 712-745", got.Min, got.Max)
+	}
+	if _, ok := soloChallenger(extremes, "kr"); ok {
+		t.Error("stale kr cutoffs were tracked")
+	}
+	// Two queues with two tiers each.
+	if len(extremes.Extremes) != 4 {
+		t.Errorf("%d watermarks, want 4", len(extremes.Extremes))
+	}
+
+	tracker.reset("2024-04-01")
+	tracker.observe(map[string]cutoff.RegionData{"euw1": regionData(760, 400)})
+	if got, _ := soloChallenger(tracker.snapshot(), "euw1"); got.Min != 760 || got.Max != 760 {
+		t.Errorf("euw1 Challenger range after the rollover = %d-%d, want 760-760", got.Min, got.Max)
+	}
+}
+
+func TestTrackExtremesRestoresFromDB(t *testing.T) {
+	fetcher := newFakeFetcher(nil)
+	u := testUpdater(t, fetcher, slotsYAML("euw1"))
+	u.db = testDB(t)
+	ctx := context.Background()
+	for _, challenger := range []int{745, 712} {
+		if err := u.db.record(ctx, time.Now(), map[string]cutoff.RegionData{"euw1": regionData(challenger, 400)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A restarted updater starts without watermarks and loads the period's
+	// from the database.
+	u.trackExtremes(ctx, map[string]cutoff.RegionData{"euw1": regionData(730, 400)})
+	if got, _ := soloChallenger(u.extremes.snapshot(), "euw1"); got.Min != 712 || got.Max != 745 {
+		t.Errorf("euw1 Challenger range = %d-%d, want 712-745 from the database", got.Min, got.Max)
+	}
+}
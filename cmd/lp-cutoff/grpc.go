@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/renja-g/lol-lp-cutoff/cutoffspb"
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// grpcServer serves the store's snapshot over gRPC.
+type grpcServer struct {
+	cutoffspb.UnimplementedCutoffServiceServer
+	store *store
+}
+
+func newGRPCServer(st *store) *grpcServer {
+	return &grpcServer{store: st}
+}
+
+func (g *grpcServer) GetCutoffs(ctx context.Context, req *cutoffspb.GetCutoffsRequest) (*cutoffspb.GetCutoffsResponse, error) {
+	data, updatedAt := g.store.get()
+	if data == nil {
+		return nil, status.Error(codes.Unavailable, "cutoffs not computed yet")
+	}
+
+	resp := &cutoffspb.GetCutoffsResponse{
+		Regions:   make(map[string]*cutoffspb.RegionCutoffs, len(data)),
+		UpdatedAt: timestamppb.New(updatedAt),
+	}
+	for region, regionData := range data {
+		resp.Regions[region] = regionCutoffsProto(region, regionData)
+	}
+	return resp, nil
+}
+
+func (g *grpcServer) GetRegionCutoffs(ctx context.Context, req *cutoffspb.GetRegionCutoffsRequest) (*cutoffspb.RegionCutoffs, error) {
+	data, _ := g.store.get()
+	if data == nil {
+		return nil, status.Error(codes.Unavailable, "cutoffs not computed yet")
+	}
+
+	regionData, ok := data[req.GetRegion()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown region %q", req.GetRegion())
+	}
+	return regionCutoffsProto(req.GetRegion(), regionData), nil
+}
+
+func regionCutoffsProto(region string, data cutoff.RegionData) *cutoffspb.RegionCutoffs {
+	return &cutoffspb.RegionCutoffs{
+		Region:         region,
+		RankedSolo_5X5: queueCutoffsProto(data.RANKED_SOLO_5x5),
+		RankedFlexSr:   queueCutoffsProto(data.RANKED_FLEX_SR),
+		Degraded:       data.Degraded,
+		UpdatedAt:      timestamppb.New(data.UpdatedAt),
+		Stale:          data.Stale,
+	}
+}
+
+// queueCutoffsProto converts cutoffs, returning nil for a disabled queue.
+func queueCutoffsProto(cutoffs cutoff.Cutoffs) *cutoffspb.QueueCutoffs {
+	if !cutoffs.Computed() {
+		return nil
+	}
+	return &cutoffspb.QueueCutoffs{
+		Challenger:  int32(cutoffs.Challenger),
+		Grandmaster: int32(cutoffs.Grandmaster),
+		Provisional: cutoffs.Provisional,
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// slowestReported is how many regions and fetches the per-cycle latency
+// summary names.
+const slowestReported = 3
+
+// regionLatency is how long a region took to process in one cycle.
+type regionLatency struct {
+	Region     string `json:"region"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// fetchLatency is how long a single league fetch took.
+type fetchLatency struct {
+	Region     string `json:"region"`
+	Queue      string `json:"queue"`
+	League     string `json:"league"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// latencyReport holds the latencies of one cycle, slowest first.
+type latencyReport struct {
+	StartedAt time.Time       `json:"startedAt"`
+	Regions   []regionLatency `json:"regions"`
+	Fetches   []fetchLatency  `json:"fetches"`
+}
+
+// latencyRecorder collects the latencies of a cycle from concurrent region
+// and fetch goroutines.
+type latencyRecorder struct {
+	mu     sync.Mutex
+	report latencyReport
+}
+
+func newLatencyRecorder(startedAt time.Time) *latencyRecorder {
+	return &latencyRecorder{report: latencyReport{StartedAt: startedAt.UTC()}}
+}
+
+func (r *latencyRecorder) region(region string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Regions = append(r.report.Regions, regionLatency{Region: region, DurationMs: d.Milliseconds()})
+}
+
+func (r *latencyRecorder) fetch(region, queueType, league string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Fetches = append(r.report.Fetches, fetchLatency{Region: region, Queue: queueType, League: league, DurationMs: d.Milliseconds()})
+}
+
+// finish returns the recorded latencies sorted slowest first.
+func (r *latencyRecorder) finish() latencyReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	sort.SliceStable(report.Regions, func(i, j int) bool { return report.Regions[i].DurationMs > report.Regions[j].DurationMs })
+	sort.SliceStable(report.Fetches, func(i, j int) bool { return report.Fetches[i].DurationMs > report.Fetches[j].DurationMs })
+	return report
+}
+
+// log summarizes the slowest regions and fetches of report at info level.
+func (report latencyReport) log() {
+	var regions, fetches []string
+	for _, l := range report.Regions[:min(slowestReported, len(report.Regions))] {
+		regions = append(regions, fmt.Sprintf("%s=%dms", l.Region, l.DurationMs))
+	}
+	for _, l := range report.Fetches[:min(slowestReported, len(report.Fetches))] {
+		fetches = append(fetches, fmt.Sprintf("%s/%s/%s=%dms", l.Region, l.Queue, l.League, l.DurationMs))
+	}
+	if len(regions) > 0 {
+		slog.Info("Slowest regions and fetches of the cycle", "regions", regions, "fetches", fetches)
+	}
+}
+
+// timedFetcher records the latency of every fetch it passes on.
+type timedFetcher struct {
+	cutoff.Fetcher
+	recorder *latencyRecorder
+}
+
+func (f timedFetcher) Fetch(ctx context.Context, region, league, queueType string) (cutoff.LeagueResponse, error) {
+	start := time.Now()
+	resp, err := f.Fetcher.Fetch(ctx, region, league, queueType)
+	f.recorder.fetch(region, queueType, league, time.Since(start))
+	return resp, err
+}
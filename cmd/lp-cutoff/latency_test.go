@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCycleRecordsLatencies(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+	fetcher.delays["kr"] = delay
+	u := testUpdater(t, fetcher, slotsYAML("euw1")+slotsYAML("kr"))
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	report := u.latencies()
+	if len(report.Regions) != 2 || report.Regions[0].Region != "kr" || report.Regions[0].DurationMs < delay.Milliseconds() {
+		t.Errorf("regions = %+v, want the delayed kr first, taking at least %s", report.Regions, delay)
+	}
+	// Three leagues of two queues per region.
+	if len(report.Fetches) != 12 {
+		t.Fatalf("%d fetches recorded, want 12", len(report.Fetches))
+	}
+	for i, f := range report.Fetches {
+		if slow := f.DurationMs >= delay.Milliseconds(); slow != (i < 6) {
+			t.Errorf("fetch %d = %+v, want the six kr fetches first and slow", i, f)
+		}
+		if i < 6 && f.Region != "kr" {
+			t.Errorf("fetch %d = %+v, want kr", i, f)
+		}
+	}
+
+	srv := testServer(nil, func(srv *server) {
+		srv.updater = u
+		srv.debugToken = "debug"
+	})
+	if w := serve(srv, http.MethodGet, "/debug/latency", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want 401", w.Code)
+	}
+	w := serve(srv, http.MethodGet, "/debug/latency", http.Header{"Authorization": {"Bearer debug"}})
+	var served latencyReport
+	if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatal(err)
+	}
+	if len(served.Regions) != 2 || served.Regions[0].Region != "kr" {
+		t.Errorf("served regions = %+v, want the cycle's", served.Regions)
+	}
+}
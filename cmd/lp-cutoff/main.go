@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/renja-g/lol-lp-cutoff/cutoffspb"
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+type RegionResult struct {
+	Region string
+	Data   cutoff.RegionData
+	Err    error
+}
+
+// runServe runs the fetch-compute-publish loop, serving the cutoffs over HTTP
+// and gRPC when configured. It is the default command. With -once it runs a
+// single cycle without the servers instead. SIGINT and SIGTERM abort the cycle
+// in flight and shut down cleanly; SIGHUP reloads the config.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	common.addFetchFlags(fs)
+	interval := fs.Duration("interval", 0, "time between cycles, overriding POLL_INTERVAL and the config file (default 1m)")
+	once := fs.Bool("once", false, "run a single cycle and exit, non-zero if a region failed, e.g. from cron or a systemd timer")
+	fs.Parse(args)
+
+	s := loadCommandSettings(common, true)
+	if *interval != 0 {
+		if *interval < minPollIntervalFloor {
+			slog.Error("Invalid settings", "error", fmt.Errorf("-interval must be at least %s, got %s", minPollIntervalFloor, *interval))
+			os.Exit(1)
+		}
+		s.PollInterval = *interval
+	}
+	slog.Info("Starting league-lp-cutoff", "version", version, "commit", commit, "build_date", buildDate)
+	if s.DryRun {
+		slog.Info("Dry run enabled, nothing will be written, uploaded or sent")
+	}
+
+	// ctx is canceled on SIGINT or SIGTERM, aborting the requests in flight.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.WithoutCancel(ctx))
+
+	watcher, err := newConfigWatcher(s.ConfigPath, s.Regions, s.StrictConfig)
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Processing regions", "regions", watcher.cfg.regionNames(), "filtered", len(s.Regions) > 0)
+
+	u := newUpdater(s, watcher)
+
+	if !s.SkipPreflight {
+		if err := preflight(ctx, u.fetcher, watcher.cfg); err != nil {
+			slog.Error("API key invalid or expired; update RIOT_API_KEY and restart", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var notifier *changeNotifier
+	if len(s.Notifiers) > 0 {
+		notifier, err = newChangeNotifier(s.Notifiers, s.ChangeThreshold, s.WebhookDebounce)
+		if err != nil {
+			slog.Error("Failed to set up notifiers", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var uploader *s3Uploader
+	if s.S3Bucket != "" {
+		uploader, err = newS3Uploader(ctx, s.S3Bucket, s.S3Endpoint, s.S3Prefix)
+		if err != nil {
+			slog.Error("Failed to set up S3 uploader", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var db *cutoffDB
+	if s.DBPath != "" && !s.DryRun {
+		db, err = openCutoffDB(s.DBPath)
+		if err != nil {
+			slog.Error("Failed to open database", "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+	}
+
+	u.notifier = notifier
+	u.uploader = uploader
+	u.db = db
+	u.warmStart()
+
+	if *once {
+		report, err := u.runCycle(ctx)
+		if notifier != nil {
+			closeNotifier(notifier)
+		}
+		failed := report.failedRegions()
+		if err != nil {
+			slog.Error("Cycle failed", "error", err)
+		} else if len(failed) > 0 {
+			slog.Error("Regions failed", "regions", failed)
+		}
+		// os.Exit skips the deferred cleanup.
+		if err := shutdownTracing(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("Flushing traces failed", "error", err)
+		}
+		if db != nil {
+			db.Close()
+		}
+		if err != nil || len(failed) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var httpSrv *http.Server
+	if s.HTTPAddr != "" {
+		handler, err := newServer(ctx, u.store, u, s)
+		if err != nil {
+			slog.Error("Failed to set up HTTP server", "error", err)
+			os.Exit(1)
+		}
+		httpSrv = &http.Server{
+			Addr:              s.HTTPAddr,
+			Handler:           handler.routes(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			slog.Info("HTTP server listening", "addr", s.HTTPAddr)
+			if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP server failed", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	var grpcSrv *grpc.Server
+	if s.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", s.GRPCAddr)
+		if err != nil {
+			slog.Error("Failed to listen for gRPC", "addr", s.GRPCAddr, "error", err)
+			os.Exit(1)
+		}
+		grpcSrv = grpc.NewServer()
+		cutoffspb.RegisterCutoffServiceServer(grpcSrv, newGRPCServer(u.store))
+		go func() {
+			slog.Info("gRPC server listening", "addr", s.GRPCAddr)
+			if err := grpcSrv.Serve(lis); err != nil {
+				slog.Error("gRPC server failed", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			slog.Info("Received SIGHUP, reloading config at the next cycle")
+			watcher.requestReload()
+		}
+	}()
+
+	failedCycles := 0
+	for ctx.Err() == nil {
+		report, err := u.runCycle(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		if errors.Is(err, riot.ErrUnauthorized) {
+			slog.Error("API key invalid or expired, every region was rejected by Riot; update RIOT_API_KEY and restart")
+			os.Exit(1)
+		}
+		if report.allFailed() {
+			failedCycles++
+		} else {
+			failedCycles = 0
+		}
+		if s.MaxFailedCycles > 0 && failedCycles >= s.MaxFailedCycles {
+			slog.Error("Every region failed for too many consecutive cycles, exiting so the process gets restarted",
+				"failed_cycles", failedCycles, "max_failed_cycles", s.MaxFailedCycles)
+			os.Exit(1)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(u.nextInterval()):
+		}
+	}
+
+	// A second signal kills the process right away.
+	stop()
+	slog.Info("Shutting down")
+	shutdownServers(httpSrv, grpcSrv)
+	if notifier != nil {
+		closeNotifier(notifier)
+	}
+}
+
+// shutdownTimeout bounds how long shutdownServers waits for the requests in
+// flight.
+const shutdownTimeout = 10 * time.Second
+
+// closeNotifier delivers the notifications still queued, giving up on those
+// not sent within shutdownTimeout.
+func closeNotifier(notifier *changeNotifier) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	notifier.close(ctx)
+}
+
+// shutdownServers stops the servers that were started, letting the requests
+// in flight finish within shutdownTimeout.
+func shutdownServers(httpSrv *http.Server, grpcSrv *grpc.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if httpSrv != nil {
+		if err := httpSrv.Shutdown(ctx); err != nil {
+			slog.Error("Shutting down HTTP server failed", "error", err)
+		}
+	}
+	if grpcSrv != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcSrv.Stop()
+		}
+	}
+}
+
+// newUpdater returns an updater fetching from Riot with the cutoff options of
+// s and no sinks; callers attach the ones they need.
+func newUpdater(s settings, watcher *configWatcher) *updater {
+	var limiter *riot.RegionLimiter
+	if s.RegionRateLimit > 0 {
+		limiter = riot.NewRegionLimiter(s.RegionRateLimit, s.RegionRateBurst, s.LeaguePriority)
+	}
+	return &updater{
+		settings: s,
+		watcher:  watcher,
+		fetcher:  riot.NewFetcher(s.APIKey, s.UserAgent, s.RiotBaseURL, riot.NewClient(s.RiotProxyURL, s.RiotTimeouts), limiter, s.PaginatedFetch),
+		opts: cutoff.Options{
+			MinLadderSize:        s.MinLadderSize,
+			ProvisionalSlotRatio: s.ProvisionalSlotRatio,
+			HistogramBucketWidth: s.HistogramBucketWidth,
+			BoundaryWindow:       s.BoundaryWindow,
+			Gaps:                 s.CutoffGaps,
+			TrackPlayers:         s.WriteChurn,
+			ChallengerOnly:       s.MinimalMode,
+			RetainLadder:         s.RetainLadder || s.ServeLadder,
+			MinLP:                s.MinPlausibleLP,
+			MaxLP:                s.MaxPlausibleLP,
+		},
+		store:     newStore(s.HistoryDepth),
+		lastGood:  make(map[string]cutoff.RegionData),
+		fetchedAt: make(map[string]time.Time),
+		cycleSem:  make(chan struct{}, 1),
+	}
+}
+
+func keepGenuineCutoffs(previous, current cutoff.RegionData) cutoff.RegionData {
+	if current.RANKED_SOLO_5x5.Provisional && !previous.RANKED_SOLO_5x5.Provisional {
+		current.RANKED_SOLO_5x5 = previous.RANKED_SOLO_5x5
+	}
+	if current.RANKED_FLEX_SR.Provisional && !previous.RANKED_FLEX_SR.Provisional {
+		current.RANKED_FLEX_SR = previous.RANKED_FLEX_SR
+	}
+	return current
+}
+
+// holdSmallChanges returns current with every cutoff that moved by at most
+// minChange LP since previous reset to its previous value, so a cutoff
+// oscillating by a point or two around the same LP doesn't churn the output.
+// Each region, queue and tier is held on its own, and always against the last
+// published value, so a slow drift still gets through once it adds up.
+func holdSmallChanges(previous, current cutoff.RegionData, minChange int) cutoff.RegionData {
+	if minChange <= 0 {
+		return current
+	}
+	for _, q := range []struct{ previous, current *cutoff.Cutoffs }{
+		{&previous.RANKED_SOLO_5x5, &current.RANKED_SOLO_5x5},
+		{&previous.RANKED_FLEX_SR, &current.RANKED_FLEX_SR},
+	} {
+		if !q.previous.Computed() || !q.current.Computed() {
+			continue
+		}
+		if abs(q.current.Challenger-q.previous.Challenger) <= minChange {
+			q.current.Challenger = q.previous.Challenger
+		}
+		if abs(q.current.Grandmaster-q.previous.Grandmaster) <= minChange {
+			q.current.Grandmaster = q.previous.Grandmaster
+		}
+	}
+	return current
+}
+
+// jumpGuard rejects cutoffs that moved implausibly far in a single cycle,
+// which is what a truncated league response from Riot looks like.
+type jumpGuard struct {
+	// MaxJumpPct is the largest change, in percent of the previous cutoff,
+	// accepted without question. Zero disables the guard.
+	MaxJumpPct float64
+	// ShrinkRatio is the fraction of the previous ladder size below which
+	// the ladder counts as anomalously shrunk.
+	ShrinkRatio float64
+}
+
+// suppressJumps returns current with every queue whose cutoffs jumped by more
+// than g.MaxJumpPct while its ladder shrank below g.ShrinkRatio of the
+// previous ladder replaced by the previous cutoffs. The kept cutoffs take the
+// new ladder size, so a shrink that persists into the next cycle is accepted
+// as genuine, e.g. after a season reset.
+func (g jumpGuard) suppressJumps(region string, previous, current cutoff.RegionData) cutoff.RegionData {
+	if g.MaxJumpPct <= 0 {
+		return current
+	}
+	current.RANKED_SOLO_5x5 = g.checkQueue(region, cutoff.QueueSoloDuo, previous.RANKED_SOLO_5x5, current.RANKED_SOLO_5x5)
+	current.RANKED_FLEX_SR = g.checkQueue(region, cutoff.QueueFlex, previous.RANKED_FLEX_SR, current.RANKED_FLEX_SR)
+	return current
+}
+
+func (g jumpGuard) checkQueue(region, queueType string, previous, current cutoff.Cutoffs) cutoff.Cutoffs {
+	if !current.Computed() || previous.Ladder.Size == 0 || float64(current.Ladder.Size) >= g.ShrinkRatio*float64(previous.Ladder.Size) {
+		return current
+	}
+	challengerJump := jumpPct(previous.Challenger, current.Challenger)
+	grandmasterJump := jumpPct(previous.Grandmaster, current.Grandmaster)
+	if challengerJump <= g.MaxJumpPct && grandmasterJump <= g.MaxJumpPct {
+		return current
+	}
+
+	slog.Warn("Rejected implausible cutoff jump, keeping previous cutoffs", "region", region, "queue", queueType,
+		"previous_challenger", previous.Challenger, "challenger", current.Challenger,
+		"previous_grandmaster", previous.Grandmaster, "grandmaster", current.Grandmaster,
+		"previous_ladder_size", previous.Ladder.Size, "ladder_size", current.Ladder.Size)
+	previous.Ladder = current.Ladder
+	return previous
+}
+
+// jumpPct returns the change from previous to current in percent of previous.
+func jumpPct(previous, current int) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return math.Abs(float64(current-previous)) / float64(previous) * 100
+}
+
+// logRegionCutoffs logs a region's cutoffs as one structured line at debug
+// level, with a group of fields per queue including the size of each fetched
+// league, and a short human-readable summary at info level.
+func logRegionCutoffs(region string, data cutoff.RegionData) {
+	attrs := []any{slog.String("region", region)}
+	if len(data.Degraded) > 0 {
+		attrs = append(attrs, slog.Any("degraded", data.Degraded))
+	}
+	var summary []string
+	for _, queue := range []struct {
+		queueType string
+		name      string
+		cutoffs   cutoff.Cutoffs
+	}{
+		{cutoff.QueueSoloDuo, "solo", data.RANKED_SOLO_5x5},
+		{cutoff.QueueFlex, "flex", data.RANKED_FLEX_SR},
+	} {
+		c := queue.cutoffs
+		if !c.Computed() {
+			continue
+		}
+		if c.ChallengerOnly {
+			attrs = append(attrs, slog.Group(queue.queueType,
+				"challenger", c.Challenger, "provisional", c.Provisional, "ladder_size", c.Ladder.Size,
+				"challenger_entries", c.Ladder.ChallengerEntries))
+			summary = append(summary, fmt.Sprintf("chall %d %s", c.Challenger, queue.name))
+			continue
+		}
+		attrs = append(attrs, slog.Group(queue.queueType,
+			"challenger", c.Challenger, "grandmaster", c.Grandmaster,
+			"provisional", c.Provisional, "ladder_size", c.Ladder.Size,
+			"challenger_entries", c.Ladder.ChallengerEntries, "grandmaster_entries", c.Ladder.GrandmasterEntries,
+			"master_entries", c.Ladder.MasterEntries))
+		summary = append(summary, fmt.Sprintf("chall %d / gm %d %s", c.Challenger, c.Grandmaster, queue.name))
+	}
+	slog.Debug("Region cutoffs", attrs...)
+	slog.Info(fmt.Sprintf("%s: %s", region, strings.Join(summary, ", ")))
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+func TestKeepGenuineCutoffs(t *testing.T) {
+	genuine := cutoff.Cutoffs{Challenger: 1300, Grandmaster: 900}
+	provisional := cutoff.Cutoffs{Challenger: cutoff.DefaultMinChallengerLP, Grandmaster: cutoff.DefaultMinGrandmasterLP, Provisional: true}
+	newer := cutoff.Cutoffs{Challenger: 1350, Grandmaster: 950}
+
+	tests := []struct {
+		name              string
+		previous, current cutoff.Cutoffs
+		want              cutoff.Cutoffs
+	}{
+		{"season reset keeps the last genuine cutoffs", genuine, provisional, genuine},
+		{"genuine cutoffs replace genuine ones", genuine, newer, newer},
+		{"nothing genuine to keep", provisional, provisional, provisional},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keepGenuineCutoffs(
+				cutoff.RegionData{RANKED_SOLO_5x5: tt.previous, RANKED_FLEX_SR: genuine},
+				cutoff.RegionData{RANKED_SOLO_5x5: tt.current, RANKED_FLEX_SR: newer},
+			)
+			if !reflect.DeepEqual(got.RANKED_SOLO_5x5, tt.want) {
+				t.Errorf("solo/duo = %+v, want %+v", got.RANKED_SOLO_5x5, tt.want)
+			}
+			if !reflect.DeepEqual(got.RANKED_FLEX_SR, newer) {
+				t.Errorf("flex = %+v, want the untouched %+v", got.RANKED_FLEX_SR, newer)
+			}
+		})
+	}
+}
+
+// computedCutoffs returns the cutoffs of a ladder of size players counting
+// down from 1500 LP by 2, with 50 Challenger and 100 Grandmaster slots.
+func computedCutoffs(size int) cutoff.Cutoffs {
+	ladder := make([]cutoff.LeagueEntry, size)
+	for i := range ladder {
+		ladder[i] = cutoff.LeagueEntry{PUUID: strconv.Itoa(i), LeaguePoints: 1500 - 2*i}
+	}
+	c := cutoff.CalculateCutoffs(ladder, cutoff.QueueConfig{Challenger: 50, Grandmaster: 100})
+	c.UpdatedAt = time.Now()
+	return c
+}
+
+func TestSuppressJumps(t *testing.T) {
+	guard := jumpGuard{MaxJumpPct: 50, ShrinkRatio: 0.5}
+	full := computedCutoffs(400)
+
+	tests := []struct {
+		name         string
+		guard        jumpGuard
+		current      cutoff.Cutoffs
+		wantPrevious bool
+	}{
+		{"truncated response", guard, computedCutoffs(3), true},
+		{"ladder grew", guard, computedCutoffs(500), false},
+		{"ladder shrank without a jump", guard, computedCutoffs(160), false},
+		{"guard disabled", jumpGuard{}, computedCutoffs(3), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.guard.suppressJumps("euw1",
+				cutoff.RegionData{RANKED_SOLO_5x5: full},
+				cutoff.RegionData{RANKED_SOLO_5x5: tt.current}).RANKED_SOLO_5x5
+			want := tt.current
+			if tt.wantPrevious {
+				want = full
+			}
+			if got.Challenger != want.Challenger || got.Grandmaster != want.Grandmaster {
+				t.Errorf("cutoffs = %d/%d, want %d/%d", got.Challenger, got.Grandmaster, want.Challenger, want.Grandmaster)
+			}
+			if got.Ladder.Size != tt.current.Ladder.Size {
+				t.Errorf("ladder size = %d, want the new %d", got.Ladder.Size, tt.current.Ladder.Size)
+			}
+		})
+	}
+}
+
+func TestSuppressJumpsAcceptsPersistentShrink(t *testing.T) {
+	guard := jumpGuard{MaxJumpPct: 50, ShrinkRatio: 0.5}
+	previous := cutoff.RegionData{RANKED_SOLO_5x5: computedCutoffs(300)}
+	truncated := computedCutoffs(3)
+
+	first := guard.suppressJumps("euw1", previous, cutoff.RegionData{RANKED_SOLO_5x5: truncated})
+	if first.RANKED_SOLO_5x5.Challenger == truncated.Challenger {
+		t.Fatal("first truncated cycle was published")
+	}
+	second := guard.suppressJumps("euw1", first, cutoff.RegionData{RANKED_SOLO_5x5: truncated})
+	if second.RANKED_SOLO_5x5.Challenger != truncated.Challenger {
+		t.Errorf("Challenger cutoff = %d, want the shrunk ladder's %d accepted on the second cycle", second.RANKED_SOLO_5x5.Challenger, truncated.Challenger)
+	}
+}
+
+// captureLogs sends the default logger's debug records to a JSON buffer for
+// the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logRecords decodes the JSON log lines in buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogRegionCutoffs(t *testing.T) {
+	buf := captureLogs(t)
+	data := regionData(900, 400)
+	data.RANKED_SOLO_5x5.Ladder = cutoff.LadderInfo{ChallengerEntries: 300, GrandmasterEntries: 700, MasterEntries: 4000, Size: 5000}
+	data.Degraded = []string{cutoff.QueueFlex + "_" + cutoff.LeagueMaster}
+	logRegionCutoffs("euw1", data)
+
+	records := logRecords(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("%d log lines, want a debug and an info line", len(records))
+	}
+	debug, info := records[0], records[1]
+	if info["msg"] != "euw1: chall 900 / gm 400 solo, chall 900 / gm 400 flex" {
+		t.Errorf("summary = %q", info["msg"])
+	}
+	solo, _ := debug[cutoff.QueueSoloDuo].(map[string]any)
+	for field, want := range map[string]float64{
+		"challenger": 900, "grandmaster": 400, "ladder_size": 5000,
+		"challenger_entries": 300, "grandmaster_entries": 700, "master_entries": 4000,
+	} {
+		if solo[field] != want {
+			t.Errorf("solo/duo %s = %v, want %v", field, solo[field], want)
+		}
+	}
+	if degraded, _ := debug["degraded"].([]any); len(degraded) != 1 || degraded[0] != data.Degraded[0] {
+		t.Errorf("degraded = %v, want %v", debug["degraded"], data.Degraded)
+	}
+}
+
+func TestHoldSmallChanges(t *testing.T) {
+	previous := regionData(1000, 500)
+	tests := []struct {
+		name      string
+		current   cutoff.RegionData
+		minChange int
+		want      [2]int
+	}{
+		{"small changes held", regionData(1002, 497), 3, [2]int{1000, 500}},
+		{"changes at the threshold held", regionData(1003, 503), 3, [2]int{1000, 500}},
+		{"larger changes published", regionData(1004, 496), 3, [2]int{1004, 496}},
+		{"each tier on its own", regionData(1010, 501), 3, [2]int{1010, 500}},
+		{"disabled", regionData(1001, 499), 0, [2]int{1001, 499}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := holdSmallChanges(previous, tt.current, tt.minChange)
+			for name, c := range map[string]cutoff.Cutoffs{"solo/duo": got.RANKED_SOLO_5x5, "flex": got.RANKED_FLEX_SR} {
+				if [2]int{c.Challenger, c.Grandmaster} != tt.want {
+					t.Errorf("%s cutoffs = %d/%d, want %v", name, c.Challenger, c.Grandmaster, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("slow drift gets through", func(t *testing.T) {
+		published := previous
+		for lp := 1001; lp <= 1004; lp++ {
+			published = holdSmallChanges(published, regionData(lp, 500), 3)
+		}
+		if got := published.RANKED_SOLO_5x5.Challenger; got != 1004 {
+			t.Errorf("Challenger after drifting 1 LP a cycle = %d, want 1004 once the drift adds up", got)
+		}
+	})
+
+	t.Run("queue missing before", func(t *testing.T) {
+		previous := regionData(1000, 500)
+		previous.RANKED_FLEX_SR = cutoff.Cutoffs{}
+		got := holdSmallChanges(previous, regionData(1001, 501), 3)
+		if got.RANKED_FLEX_SR.Challenger != 1001 || got.RANKED_SOLO_5x5.Challenger != 1000 {
+			t.Errorf("Challenger cutoffs = %d solo/duo, %d flex, want 1000 held and 1001 published", got.RANKED_SOLO_5x5.Challenger, got.RANKED_FLEX_SR.Challenger)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier delivers a significant cutoff change to one destination, giving up
+// when ctx is done.
+type Notifier interface {
+	Notify(ctx context.Context, change cutoffChange) error
+}
+
+// notifierKinds registers the notifier kinds that can be configured through
+// NOTIFIERS, each built from the target given with it.
+var notifierKinds = map[string]func(target string) (Notifier, error){
+	"webhook": newWebhookSink,
+	"slack":   newSlackSink,
+	"discord": newDiscordSink,
+}
+
+// notifierSpec configures one notifier as "<kind>=<target>".
+type notifierSpec struct {
+	Kind   string
+	Target string
+}
+
+// parseNotifiers parses the comma-separated NOTIFIERS list of notifier specs.
+func parseNotifiers(value string) ([]notifierSpec, error) {
+	var specs []notifierSpec
+	for _, item := range splitList(value) {
+		kind, target, ok := strings.Cut(item, "=")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if !ok || strings.TrimSpace(target) == "" {
+			return nil, fmt.Errorf("NOTIFIERS entry %q must be formatted as <kind>=<target>", item)
+		}
+		if _, known := notifierKinds[kind]; !known {
+			return nil, fmt.Errorf("NOTIFIERS entry %q has unknown kind, expected one of %s", item, strings.Join(notifierKindNames(), ", "))
+		}
+		specs = append(specs, notifierSpec{Kind: kind, Target: strings.TrimSpace(target)})
+	}
+	return specs, nil
+}
+
+func notifierKindNames() []string {
+	names := make([]string, 0, len(notifierKinds))
+	for name := range notifierKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// changeNotifier fans significant cutoff changes out to every configured
+// notifier. Each notifier delivers from its own queue on a background
+// goroutine, so a slow or failing one neither stalls the fetch loop nor the
+// other notifiers; events are dropped when a queue is full.
+type changeNotifier struct {
+	threshold int
+	debounce  time.Duration
+	sinks     []*notifierSink
+	lastSent  map[string]time.Time
+	// running tracks the sinks' delivery goroutines for close.
+	running sync.WaitGroup
+	// ctx is the context of every delivery, canceled by close once its
+	// deadline passes.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type notifierSink struct {
+	kind     string
+	notifier Notifier
+	queue    chan cutoffChange
+}
+
+// newChangeNotifier builds the notifiers of specs, which must have been
+// parsed by parseNotifiers.
+func newChangeNotifier(specs []notifierSpec, threshold int, debounce time.Duration) (*changeNotifier, error) {
+	n := &changeNotifier{
+		threshold: threshold,
+		debounce:  debounce,
+		lastSent:  make(map[string]time.Time),
+	}
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	for _, spec := range specs {
+		notifier, err := notifierKinds[spec.Kind](spec.Target)
+		if err != nil {
+			return nil, fmt.Errorf("set up %s notifier: %w", spec.Kind, err)
+		}
+		n.add(spec.Kind, notifier)
+	}
+	return n, nil
+}
+
+// add starts delivering to notifier.
+func (n *changeNotifier) add(kind string, notifier Notifier) {
+	sink := &notifierSink{kind: kind, notifier: notifier, queue: make(chan cutoffChange, 100)}
+	n.sinks = append(n.sinks, sink)
+	n.running.Add(1)
+	go func() {
+		defer n.running.Done()
+		sink.run(n.ctx)
+	}()
+}
+
+// close stops accepting changes and waits until every queued change was
+// delivered, or until ctx is done: the deliveries in flight are then aborted
+// and the changes still queued dropped, so a slow endpoint can't hold up
+// shutdown. notify must not be called afterwards.
+func (n *changeNotifier) close(ctx context.Context) {
+	for _, sink := range n.sinks {
+		close(sink.queue)
+	}
+	done := make(chan struct{})
+	go func() {
+		n.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		n.cancel()
+		<-done
+	}
+	n.cancel()
+}
+
+// notify queues every change whose magnitude exceeds the threshold, skipping
+// cutoffs that already triggered a notification within the debounce window.
+// It must only be called from a single goroutine.
+func (n *changeNotifier) notify(changes []cutoffChange) {
+	now := time.Now()
+	for _, change := range changes {
+		if abs(change.Delta) <= n.threshold {
+			continue
+		}
+		key := change.Region + "_" + change.Queue + "_" + change.Tier
+		if last, ok := n.lastSent[key]; ok && now.Sub(last) < n.debounce {
+			continue
+		}
+		n.lastSent[key] = now
+
+		for _, sink := range n.sinks {
+			select {
+			case sink.queue <- change:
+			default:
+				slog.Warn("Notifier queue full, dropping change", "notifier", sink.kind,
+					"region", change.Region, "queue", change.Queue, "tier", change.Tier)
+			}
+		}
+	}
+}
+
+func (s *notifierSink) run(ctx context.Context) {
+	dropped := 0
+	for change := range s.queue {
+		if ctx.Err() != nil {
+			dropped++
+			continue
+		}
+		if err := s.notifier.Notify(ctx, change); err != nil {
+			slog.Error("Sending notification failed", "notifier", s.kind,
+				"region", change.Region, "queue", change.Queue, "tier", change.Tier, "error", err)
+		}
+	}
+	if dropped > 0 {
+		slog.Warn("Notifier shut down with changes still queued, dropping them", "notifier", s.kind, "dropped", dropped)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotifier records the changes it was asked to deliver, blocking each
+// delivery until release is closed or the delivery is aborted when release is
+// set.
+type fakeNotifier struct {
+	mu      sync.Mutex
+	got     []cutoffChange
+	aborted int
+	err     error
+	release chan struct{}
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, change cutoffChange) error {
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.aborted++
+			return ctx.Err()
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.got = append(f.got, change)
+	return f.err
+}
+
+func (f *fakeNotifier) delivered() []cutoffChange {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.got)
+}
+
+// testChange is a Challenger cutoff change of delta LP in region.
+func testChange(region string, delta int) cutoffChange {
+	return cutoffChange{Region: region, Queue: "RANKED_SOLO_5x5", Tier: "challenger", Delta: delta}
+}
+
+func TestChangeNotifierThresholdAndDebounce(t *testing.T) {
+	n, err := newChangeNotifier(nil, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeNotifier{}
+	n.add("fake", fake)
+
+	n.notify([]cutoffChange{testChange("euw1", 10), testChange("euw1", -11), testChange("kr", 50)})
+	// Within the debounce window of the first batch.
+	n.notify([]cutoffChange{testChange("euw1", 30), testChange("na1", 12)})
+	n.close(context.Background())
+
+	want := []cutoffChange{testChange("euw1", -11), testChange("kr", 50), testChange("na1", 12)}
+	if got := fake.delivered(); !slices.Equal(got, want) {
+		t.Errorf("delivered %+v, want %+v", got, want)
+	}
+}
+
+func TestChangeNotifierFanOut(t *testing.T) {
+	n, err := newChangeNotifier(nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow := &fakeNotifier{release: make(chan struct{})}
+	failing := &fakeNotifier{err: errors.New("webhook returned 500")}
+	fast := &fakeNotifier{}
+	n.add("slow", slow)
+	n.add("failing", failing)
+	n.add("fast", fast)
+
+	changes := []cutoffChange{testChange("euw1", 20), testChange("kr", -30)}
+	n.notify(changes)
+
+	// The slow notifier holds up neither the others nor notify.
+	deadline := time.Now().Add(time.Second)
+	for len(fast.delivered()) < len(changes) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := fast.delivered(); !slices.Equal(got, changes) {
+		t.Errorf("fast notifier got %+v while the slow one was stuck, want %+v", got, changes)
+	}
+
+	close(slow.release)
+	n.close(context.Background())
+	for name, f := range map[string]*fakeNotifier{"slow": slow, "failing": failing} {
+		if got := f.delivered(); !slices.Equal(got, changes) {
+			t.Errorf("%s notifier got %+v, want %+v", name, got, changes)
+		}
+	}
+}
+
+func TestChangeNotifierDropsWhenQueueFull(t *testing.T) {
+	n, err := newChangeNotifier(nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stuck := &fakeNotifier{release: make(chan struct{})}
+	n.add("stuck", stuck)
+
+	var changes []cutoffChange
+	for i := range 150 {
+		changes = append(changes, testChange(fmt.Sprintf("region%d", i), 20))
+	}
+	n.notify(changes)
+	close(stuck.release)
+	n.close(context.Background())
+
+	// One change is being delivered when the queue of 100 fills up.
+	if got := len(stuck.delivered()); got < 100 || got > 101 {
+		t.Errorf("delivered %d changes, want the queue's 100 plus at most the one in flight", got)
+	}
+}
+
+func TestParseNotifiers(t *testing.T) {
+	specs, err := parseNotifiers(" webhook=https://example.com/hook , Slack=https://hooks.slack.com/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []notifierSpec{{"webhook", "https://example.com/hook"}, {"slack", "https://hooks.slack.com/x"}}
+	if !slices.Equal(specs, want) {
+		t.Errorf("specs = %+v, want %+v", specs, want)
+	}
+	for _, value := range []string{"webhook", "webhook=", "pager=https://example.com"} {
+		if _, err := parseNotifiers(value); err == nil {
+			t.Errorf("parseNotifiers(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestChangeNotifierCloseDeadline(t *testing.T) {
+	n, err := newChangeNotifier(nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stuck := &fakeNotifier{release: make(chan struct{})}
+	fast := &fakeNotifier{}
+	n.add("stuck", stuck)
+	n.add("fast", fast)
+	changes := []cutoffChange{testChange("euw1", 20), testChange("kr", 30), testChange("na1", 40)}
+	n.notify(changes)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	n.close(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close took %s, want it to give up at its deadline", elapsed)
+	}
+
+	stuck.mu.Lock()
+	defer stuck.mu.Unlock()
+	if stuck.aborted != 1 || len(stuck.got) != 0 {
+		t.Errorf("stuck notifier aborted %d and delivered %d, want the one in flight aborted and the rest dropped", stuck.aborted, len(stuck.got))
+	}
+	if got := fast.delivered(); !slices.Equal(got, changes) {
+		t.Errorf("fast notifier got %+v, want %+v before the deadline", got, changes)
+	}
+}
+
+func TestWebhookNotifyHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	var got cutoffChange
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	sink, err := newWebhookSink(srv.URL + "/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Notify(context.Background(), testChange("euw1", 20)); err != nil {
+		t.Fatal(err)
+	}
+	if got != testChange("euw1", 20) {
+		t.Errorf("webhook received %+v, want %+v", got, testChange("euw1", 20))
+	}
+
+	slow, err := newWebhookSink(srv.URL + "/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := slow.Notify(ctx, testChange("euw1", 20)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Notify took %s, want it to give up with its context", elapsed)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// knownPlatforms maps the Riot platform routing values that serve the
+// league-v4 endpoints to the regional routing value of their continent, which
+// the regional endpoints such as match-v5 are called on.
+var knownPlatforms = map[string]string{
+	"br1":  "americas",
+	"eun1": "europe",
+	"euw1": "europe",
+	"jp1":  "asia",
+	"kr":   "asia",
+	"la1":  "americas",
+	"la2":  "americas",
+	"me1":  "europe",
+	"na1":  "americas",
+	"oc1":  "sea",
+	"ph2":  "sea",
+	"ru":   "europe",
+	"sg2":  "sea",
+	"th2":  "sea",
+	"tr1":  "europe",
+	"tw2":  "sea",
+	"vn2":  "sea",
+}
+
+// regionalRoute returns the regional routing value, e.g. "europe" for
+// "euw1", that serves the continent of platform. The SEA platforms route to
+// "sea", which match-v5 uses; account-v1 has no "sea" route and serves those
+// accounts from any of the others.
+func regionalRoute(platform string) (string, error) {
+	route, ok := knownPlatforms[platform]
+	if !ok {
+		return "", fmt.Errorf("unknown platform %q, expected one of %s", platform, strings.Join(platformCodes(), ", "))
+	}
+	return route, nil
+}
+
+// platformAliases maps the commonly used region names to their platform
+// routing value.
+var platformAliases = map[string]string{
+	"br":   "br1",
+	"eune": "eun1",
+	"euw":  "euw1",
+	"jp":   "jp1",
+	"lan":  "la1",
+	"las":  "la2",
+	"me":   "me1",
+	"na":   "na1",
+	"oce":  "oc1",
+	"oc":   "oc1",
+	"ph":   "ph2",
+	"sg":   "sg2",
+	"th":   "th2",
+	"tr":   "tr1",
+	"tw":   "tw2",
+	"vn":   "vn2",
+}
+
+// normalizePlatform returns the platform routing value for region, resolving
+// aliases and case. Unknown values are returned lowercased and unchanged
+// otherwise so validation can report them.
+func normalizePlatform(region string) string {
+	region = strings.ToLower(strings.TrimSpace(region))
+	if platform, ok := platformAliases[region]; ok {
+		return platform
+	}
+	return region
+}
+
+// platformCodes returns the known platform routing values in sorted order.
+func platformCodes() []string {
+	codes := make([]string, 0, len(knownPlatforms))
+	for code := range knownPlatforms {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
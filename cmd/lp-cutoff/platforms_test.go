@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRegionalRoute(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{"br1", "americas"},
+		{"eun1", "europe"},
+		{"euw1", "europe"},
+		{"jp1", "asia"},
+		{"kr", "asia"},
+		{"la1", "americas"},
+		{"la2", "americas"},
+		{"me1", "europe"},
+		{"na1", "americas"},
+		{"oc1", "sea"},
+		{"ph2", "sea"},
+		{"ru", "europe"},
+		{"sg2", "sea"},
+		{"th2", "sea"},
+		{"tr1", "europe"},
+		{"tw2", "sea"},
+		{"vn2", "sea"},
+	}
+	var covered []string
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			got, err := regionalRoute(tt.platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("regionalRoute(%q) = %q, want %q", tt.platform, got, tt.want)
+			}
+		})
+		covered = append(covered, tt.platform)
+	}
+	if codes := platformCodes(); !slices.Equal(codes, covered) {
+		t.Errorf("known platforms = %v, want every one covered here: %v", codes, covered)
+	}
+
+	if _, err := regionalRoute("euw"); err == nil {
+		t.Error("regionalRoute accepted the alias euw, want only platform codes")
+	}
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{"euw1", "euw1"},
+		{"EUW", "euw1"},
+		{" eune ", "eun1"},
+		{"lan", "la1"},
+		{"las", "la2"},
+		{"oce", "oc1"},
+		{"oc", "oc1"},
+		{"KR", "kr"},
+		{"xx9", "xx9"},
+	}
+	for _, tt := range tests {
+		if got := normalizePlatform(tt.region); got != tt.want {
+			t.Errorf("normalizePlatform(%q) = %q, want %q", tt.region, got, tt.want)
+		}
+	}
+	for alias, platform := range platformAliases {
+		if _, ok := knownPlatforms[platform]; !ok {
+			t.Errorf("alias %q resolves to unknown platform %q", alias, platform)
+		}
+	}
+}
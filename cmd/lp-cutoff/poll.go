@@ -0,0 +1,34 @@
+package main
+
+import "time"
+
+// minPollIntervalFloor is the shortest poll interval ever used, regardless of
+// configuration or how much rate-limit budget is left.
+const minPollIntervalFloor = 10 * time.Second
+
+// rateLimitReporter is implemented by fetchers that observe Riot's rate-limit
+// headers, such as riot.Fetcher.
+type rateLimitReporter interface {
+	// TakeRateUsage returns the highest fraction of any rate-limit window
+	// used since the previous call, and false when nothing was observed.
+	TakeRateUsage() (float64, bool)
+}
+
+// nextPollInterval scales the base interval with the rate-limit usage observed
+// during the last cycle: plenty of budget shortens the wait, a nearly
+// exhausted budget lengthens it. The result stays within [minInterval,
+// maxInterval] and never drops below minPollIntervalFloor.
+func nextPollInterval(base, minInterval, maxInterval time.Duration, usage float64, observed bool) time.Duration {
+	next := base
+	if observed {
+		switch {
+		case usage >= 0.9:
+			next = base * 4
+		case usage >= 0.75:
+			next = base * 2
+		case usage <= 0.25:
+			next = base / 2
+		}
+	}
+	return max(min(next, maxInterval), minInterval, minPollIntervalFloor)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+const (
+	preflightAttempts = 3
+	preflightDelay    = 5 * time.Second
+)
+
+// preflight checks the API key and connectivity before the first cycle by
+// fetching the Challenger league, the smallest apex league, of one
+// configured region. It returns an error only when Riot rejects the key;
+// other failures are retried briefly and then left for the regular cycles
+// to report.
+func preflight(ctx context.Context, fetcher cutoff.Fetcher, cfg config) error {
+	region, queueType, ok := preflightTarget(cfg)
+	if !ok {
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		_, err := fetcher.Fetch(reqCtx, region, cutoff.LeagueChallenger, queueType)
+		cancel()
+		if err == nil {
+			slog.Info("Preflight check passed", "region", region, "queue", queueType)
+			return nil
+		}
+		if errors.Is(err, riot.ErrUnauthorized) {
+			return fmt.Errorf("preflight against %s: %w", region, err)
+		}
+		if attempt == preflightAttempts {
+			slog.Warn("Preflight check failed, continuing anyway", "region", region, "attempts", attempt, "error", err)
+			return nil
+		}
+		slog.Warn("Preflight check failed, retrying", "region", region, "attempt", attempt, "error", err)
+		select {
+		case <-time.After(preflightDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// preflightTarget returns the first region, in sorted order, with an enabled
+// queue, and that queue.
+func preflightTarget(cfg config) (region, queueType string, ok bool) {
+	for _, region := range cfg.regionNames() {
+		queues := cfg.Regions[region]
+		if queues.SoloDuo.IsEnabled() {
+			return region, cutoff.QueueSoloDuo, true
+		}
+		if queues.Flex.IsEnabled() {
+			return region, cutoff.QueueFlex, true
+		}
+	}
+	return "", "", false
+}
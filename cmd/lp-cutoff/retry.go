@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+// retryBudget is the number of retries left in a cycle, shared by all of its
+// regions so a broad outage can't stretch the cycle with retries.
+type retryBudget struct {
+	mu   sync.Mutex
+	left int
+}
+
+// take uses up one retry and reports whether one was left.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.left <= 0 {
+		return false
+	}
+	b.left--
+	return true
+}
+
+// retryingFetcher retries fetches failing with riot.ErrTransient, up to
+// maxRetries times per fetch with exponential backoff, as long as the budget
+// lasts.
+type retryingFetcher struct {
+	cutoff.Fetcher
+	budget     *retryBudget
+	maxRetries int
+	backoff    time.Duration
+}
+
+func (f retryingFetcher) Fetch(ctx context.Context, region, league, queueType string) (cutoff.LeagueResponse, error) {
+	delay := f.backoff
+	for retry := 1; ; retry++ {
+		resp, err := f.Fetcher.Fetch(ctx, region, league, queueType)
+		if err == nil || !errors.Is(err, riot.ErrTransient) || retry > f.maxRetries {
+			return resp, err
+		}
+		if !f.budget.take() {
+			slog.Warn("Retry budget exhausted, not retrying", "region", region, "queue", queueType, "tier", league, "error", err)
+			return resp, err
+		}
+		slog.Warn("Retrying fetch", "region", region, "queue", queueType, "tier", league, "retry", retry, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		}
+		delay *= 2
+	}
+}
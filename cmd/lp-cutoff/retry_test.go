@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+func TestRetryingFetcher(t *testing.T) {
+	transient := fmt.Errorf("riot returned 503: %w", riot.ErrTransient)
+	tests := []struct {
+		name       string
+		err        error
+		budget     int
+		maxRetries int
+		wantCalls  int
+		wantLeft   int
+	}{
+		{"transient failures retried up to maxRetries", transient, 10, 2, 3, 8},
+		{"budget stops retries", transient, 1, 2, 2, 0},
+		{"empty budget", transient, 0, 2, 1, 0},
+		{"permanent failures not retried", fmt.Errorf("riot rejected the key"), 10, 2, 1, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := newFakeFetcher(map[string]int{"euw1": 1500})
+			fetcher.set("euw1", 1500, tt.err)
+			budget := &retryBudget{left: tt.budget}
+			f := retryingFetcher{Fetcher: fetcher, budget: budget, maxRetries: tt.maxRetries, backoff: time.Millisecond}
+
+			if _, err := f.Fetch(context.Background(), "euw1", "challenger", "RANKED_SOLO_5x5"); err == nil {
+				t.Error("Fetch succeeded, want the failure returned")
+			}
+			if calls := fetcher.callsTo("euw1"); calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if budget.left != tt.wantLeft {
+				t.Errorf("budget left = %d, want %d", budget.left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestRetryBudgetIsSharedByTheCycle(t *testing.T) {
+	// Every fetch of either region fails; with a budget of 3 the whole cycle
+	// makes exactly three fetches more than without retries.
+	calls := make(map[string]int)
+	for _, budget := range []string{"0", "3"} {
+		fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+		fetcher.set("euw1", 1500, fmt.Errorf("riot returned 503: %w", riot.ErrTransient))
+		fetcher.set("kr", 1800, fmt.Errorf("riot returned 503: %w", riot.ErrTransient))
+		u := testUpdater(t, fetcher, slotsYAML("euw1")+slotsYAML("kr"), "RETRY_BUDGET", budget, "MAX_RETRIES", "5", "RETRY_BACKOFF", "1ms")
+		u.runCycle(context.Background())
+		calls[budget] = fetcher.callsTo("euw1") + fetcher.callsTo("kr")
+	}
+	if calls["3"]-calls["0"] != 3 {
+		t.Errorf("fetches with a budget of 3 = %d, without retries = %d, want 3 more", calls["3"], calls["0"])
+	}
+}
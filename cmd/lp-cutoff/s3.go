@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3Uploader copies written output files to an S3-compatible bucket. Custom
+// endpoints (MinIO, Cloudflare R2, ...) use path-style addressing.
+type s3Uploader struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Uploader(ctx context.Context, bucket, endpoint, prefix string) (*s3Uploader, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	if cfg.Region == "" && endpoint != "" {
+		// R2 and most self-hosted stores ignore the region but the SDK
+		// requires one to sign requests.
+		cfg.Region = "auto"
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &s3Uploader{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+// uploadFiles uploads the given files, named relative to outputDir, keeping
+// their relative path under the configured prefix.
+func (u *s3Uploader) uploadFiles(ctx context.Context, outputDir string, relPaths ...string) error {
+	for _, relPath := range relPaths {
+		data, err := os.ReadFile(filepath.Join(outputDir, relPath))
+		if err != nil {
+			return fmt.Errorf("read %s for upload: %w", relPath, err)
+		}
+
+		key := path.Join(u.prefix, filepath.ToSlash(relPath))
+		_, err = u.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:       aws.String(u.bucket),
+			Key:          aws.String(key),
+			Body:         bytes.NewReader(data),
+			ContentType:  aws.String("application/json"),
+			CacheControl: aws.String("public, max-age=60"),
+		})
+		if err != nil {
+			return fmt.Errorf("upload %s to s3://%s/%s: %w", relPath, u.bucket, key, err)
+		}
+	}
+	return nil
+}
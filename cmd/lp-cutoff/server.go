@@ -0,0 +1,527 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+const maxHistoryDays = 366
+
+// maxFreshnessWait is the default freshnessWait of a server.
+const maxFreshnessWait = 30 * time.Second
+
+// server exposes the cutoffs over HTTP.
+type server struct {
+	// ctx is the serve context, the parent of the cycles started by
+	// refreshes, so shutting down cancels them.
+	ctx            context.Context
+	store          *store
+	updater        *updater
+	refreshToken   string
+	debugToken     string
+	outputDir      string
+	archiveLayout  output.ArchiveLayout
+	allowedOrigins []string
+	archive        *archiveCache
+	// retainLadder enables the custom cutoff endpoint, which needs the
+	// ladders kept in memory; serveLadder enables the ladder endpoint.
+	retainLadder bool
+	serveLadder  bool
+	// dashboard enables the HTML overview at /.
+	dashboard bool
+	// files serves the output directory under /files/ when enabled.
+	files http.Handler
+	// freshnessWait bounds how long a request with max_age waits for the
+	// refresh it triggered.
+	freshnessWait time.Duration
+}
+
+func newServer(ctx context.Context, st *store, u *updater, s settings) (*server, error) {
+	srv := &server{
+		ctx:            ctx,
+		store:          st,
+		updater:        u,
+		refreshToken:   s.RefreshToken,
+		debugToken:     s.DebugToken,
+		outputDir:      s.OutputDir,
+		archiveLayout:  s.Archive,
+		allowedOrigins: s.AllowedOrigins,
+		archive:        newArchiveCache(),
+		retainLadder:   s.RetainLadder,
+		serveLadder:    s.ServeLadder,
+		dashboard:      s.ServeDashboard,
+		freshnessWait:  maxFreshnessWait,
+	}
+	if s.ServeFiles {
+		files, err := newStaticFiles(s.OutputDir)
+		if err != nil {
+			return nil, err
+		}
+		srv.files = files
+	}
+	return srv, nil
+}
+
+func (srv *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	srv.handlePublic(mux, "/cutoffs", gzipped(srv.handleCutoffs))
+	srv.handlePublic(mux, "/cutoffs/history", gzipped(srv.handleHistory))
+	srv.handlePublic(mux, "/cutoffs/{date}", gzipped(srv.handleCutoffsByDate))
+	if srv.retainLadder {
+		srv.handlePublic(mux, "/cutoffs/{region}/custom", srv.handleCustomCutoff)
+	}
+	if srv.serveLadder {
+		srv.handlePublic(mux, "/ladder/{region}/{queue}", gzipped(srv.handleLadder))
+	}
+	srv.handlePublic(mux, "/history/recent", gzipped(srv.handleRecentHistory))
+	srv.handlePublic(mux, "/summary", gzipped(srv.handleSummary))
+	mux.HandleFunc("GET /version", srv.handleVersion)
+	mux.HandleFunc("POST /refresh", srv.handleRefresh)
+	if srv.debugToken != "" {
+		mux.HandleFunc("GET /debug", srv.handleDebug)
+		mux.HandleFunc("GET /debug/latency", srv.handleDebugLatency)
+	}
+	if srv.dashboard {
+		mux.HandleFunc("GET /{$}", srv.handleDashboard)
+	}
+	if srv.files != nil {
+		srv.handlePublic(mux, "/files/", http.StripPrefix("/files", srv.files).ServeHTTP)
+	}
+	return mux
+}
+
+// handlePublic registers h for GET requests on path with CORS headers so
+// browsers can call it directly, and answers the CORS preflight for path.
+func (srv *server) handlePublic(mux *http.ServeMux, path string, h http.HandlerFunc) {
+	mux.Handle("GET "+path, srv.cors(h))
+	mux.Handle("OPTIONS "+path, srv.cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+}
+
+// cors sets the CORS response headers when the request's origin is allowed.
+func (srv *server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if allowOrigin, ok := srv.allowOrigin(origin); ok {
+				h := w.Header()
+				h.Set("Access-Control-Allow-Origin", allowOrigin)
+				h.Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+				h.Set("Access-Control-Allow-Headers", "Content-Type")
+				h.Set("Access-Control-Max-Age", "86400")
+				if allowOrigin != "*" {
+					h.Add("Vary", "Origin")
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin and
+// whether the origin is allowed at all.
+func (srv *server) allowOrigin(origin string) (string, bool) {
+	for _, allowed := range srv.allowedOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// handleCutoffs serves the latest in-memory snapshot in the shape of
+// cutoffs.json, schemaVersion included. With ?max_age=N a
+// snapshot older than N seconds is refreshed first, joining any refresh
+// already running; when no fresh enough snapshot is available within
+// freshnessWait the request fails with 503. Forcing a refresh this way
+// requires the refresh token, like POST /refresh, when one is configured.
+func (srv *server) handleCutoffs(w http.ResponseWriter, r *http.Request) {
+	data, updatedAt := srv.store.get()
+	if value := r.URL.Query().Get("max_age"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < int(minPollIntervalFloor/time.Second) {
+			writeError(w, http.StatusBadRequest, "max_age must be at least "+strconv.Itoa(int(minPollIntervalFloor/time.Second))+" seconds")
+			return
+		}
+		maxAge := time.Duration(seconds) * time.Second
+		if data == nil || time.Since(updatedAt) > maxAge {
+			if srv.refreshToken != "" && !hasBearerToken(r, srv.refreshToken) {
+				writeError(w, http.StatusUnauthorized, "max_age needs the refresh token to refresh stale cutoffs")
+				return
+			}
+			waitCtx, cancel := context.WithTimeout(r.Context(), srv.freshnessWait)
+			_, err := srv.updater.refresh(srv.ctx, waitCtx)
+			cancel()
+			if r.Context().Err() != nil {
+				return
+			}
+			data, updatedAt = srv.store.get()
+			if err != nil || data == nil || time.Since(updatedAt) > maxAge {
+				writeError(w, http.StatusServiceUnavailable, "no cutoffs fresher than max_age available")
+				return
+			}
+		}
+	}
+	if data == nil {
+		writeError(w, http.StatusServiceUnavailable, "cutoffs not computed yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, output.File{SchemaVersion: output.SchemaVersion, Regions: data})
+}
+
+// handleSummary serves per-queue and per-tier aggregates across all regions,
+// computed from the current snapshot.
+func (srv *server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	data, _ := srv.store.get()
+	if data == nil {
+		writeError(w, http.StatusServiceUnavailable, "cutoffs not computed yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, summarizeCutoffs(data))
+}
+
+type customCutoff struct {
+	Region     string  `json:"region"`
+	Queue      string  `json:"queue"`
+	N          int     `json:"n,omitempty"`
+	Percentile float64 `json:"percentile,omitempty"`
+	// Rank is the 1-based ladder rank the LP was read at, fractional for
+	// percentiles.
+	Rank       float64 `json:"rank"`
+	LP         float64 `json:"lp"`
+	LadderSize int     `json:"ladderSize"`
+}
+
+// handleCustomCutoff serves the LP of the player ranked n in a region's
+// current ladder, i.e. the cutoff of a tier with n slots, or of the player at
+// a percentile of the ladder. A percentile's fractional rank is truncated
+// unless ?interpolate=true asks for the LP interpolated between the two
+// ranks around it. The queue defaults to solo/duo.
+func (srv *server) handleCustomCutoff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var n int
+	var percentile float64
+	var err error
+	switch {
+	case query.Has("n") == query.Has("percentile"):
+		writeError(w, http.StatusBadRequest, "exactly one of n and percentile is required")
+		return
+	case query.Has("n"):
+		if n, err = strconv.Atoi(query.Get("n")); err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "n must be a positive integer")
+			return
+		}
+	default:
+		if percentile, err = strconv.ParseFloat(query.Get("percentile"), 64); err != nil || !(percentile > 0 && percentile <= 100) {
+			writeError(w, http.StatusBadRequest, "percentile must be greater than 0 and at most 100")
+			return
+		}
+	}
+	interpolate, err := strconv.ParseBool(cmp.Or(query.Get("interpolate"), "false"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "interpolate must be true or false")
+		return
+	}
+	queue := query.Get("queue")
+	if queue == "" {
+		queue = cutoff.QueueSoloDuo
+	}
+	if queue != cutoff.QueueSoloDuo && queue != cutoff.QueueFlex {
+		writeError(w, http.StatusBadRequest, "queue must be "+cutoff.QueueSoloDuo+" or "+cutoff.QueueFlex)
+		return
+	}
+
+	region := r.PathValue("region")
+	cutoffs, ok := srv.queueCutoffs(w, region, queue)
+	if !ok {
+		return
+	}
+	rank := float64(n)
+	if percentile > 0 {
+		rank = max(percentile/100*float64(len(cutoffs.Entries)), 1)
+	}
+	lp, ok := cutoff.LPAtRank(cutoffs.Entries, rank, interpolate)
+	if !ok {
+		writeError(w, http.StatusNotFound, "the ladder has only "+strconv.Itoa(len(cutoffs.Entries))+" players")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, customCutoff{
+		Region:     region,
+		Queue:      queue,
+		N:          n,
+		Percentile: percentile,
+		Rank:       rank,
+		LP:         lp,
+		LadderSize: len(cutoffs.Entries),
+	})
+}
+
+// queueCutoffs looks up the current cutoffs of queue in region, writing the
+// error response and returning false when there are none.
+func (srv *server) queueCutoffs(w http.ResponseWriter, region, queue string) (cutoff.Cutoffs, bool) {
+	data, _ := srv.store.get()
+	if data == nil {
+		writeError(w, http.StatusServiceUnavailable, "cutoffs not computed yet")
+		return cutoff.Cutoffs{}, false
+	}
+	regionData, ok := data[region]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown region "+region)
+		return cutoff.Cutoffs{}, false
+	}
+	cutoffs := regionData.RANKED_SOLO_5x5
+	if queue == cutoff.QueueFlex {
+		cutoffs = regionData.RANKED_FLEX_SR
+	}
+	if !cutoffs.Computed() {
+		writeError(w, http.StatusNotFound, queue+" is not computed for "+region)
+		return cutoff.Cutoffs{}, false
+	}
+	return cutoffs, true
+}
+
+type ladderResponse struct {
+	Region string `json:"region"`
+	Queue  string `json:"queue"`
+	Size   int    `json:"size"`
+	// LP lists the players' LP, highest first; Entries replaces it with
+	// the full league entries when requested with ?entries=true.
+	LP      []int                `json:"lp,omitempty"`
+	Entries []cutoff.LeagueEntry `json:"entries,omitempty"`
+}
+
+// handleLadder serves a region's current ladder of a queue, sorted by LP
+// highest first, capped to ?limit=N players.
+func (srv *server) handleLadder(w http.ResponseWriter, r *http.Request) {
+	queue := r.PathValue("queue")
+	if queue != cutoff.QueueSoloDuo && queue != cutoff.QueueFlex {
+		writeError(w, http.StatusBadRequest, "queue must be "+cutoff.QueueSoloDuo+" or "+cutoff.QueueFlex)
+		return
+	}
+	limit := -1
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+	withEntries, err := strconv.ParseBool(cmp.Or(r.URL.Query().Get("entries"), "false"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "entries must be true or false")
+		return
+	}
+
+	region := r.PathValue("region")
+	cutoffs, ok := srv.queueCutoffs(w, region, queue)
+	if !ok {
+		return
+	}
+	entries := cutoffs.Entries
+	if limit >= 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+
+	resp := ladderResponse{Region: region, Queue: queue, Size: len(cutoffs.Entries)}
+	if withEntries {
+		resp.Entries = entries
+	} else {
+		resp.LP = make([]int, len(entries))
+		for i, entry := range entries {
+			resp.LP[i] = entry.LeaguePoints
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleCutoffsByDate serves the archived cutoffs of a single day.
+func (srv *server) handleCutoffsByDate(w http.ResponseWriter, r *http.Request) {
+	date, err := time.ParseInLocation("2006-01-02", r.PathValue("date"), srv.archiveLayout.Location)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "date must be formatted as YYYY-MM-DD")
+		return
+	}
+
+	data, err := srv.archive.read(srv.archiveLayout.Path(srv.outputDir, date))
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusNotFound, "no cutoffs archived for "+date.Format("2006-01-02"))
+		return
+	}
+	if err != nil {
+		slog.Error("Reading archive failed", "date", date.Format("2006-01-02"), "error", err)
+		writeError(w, http.StatusInternalServerError, "reading archive failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+type historyPoint struct {
+	Date string            `json:"date"`
+	Data cutoff.RegionData `json:"data"`
+}
+
+// handleHistory serves a time series of one region's archived cutoffs, oldest
+// first. Days without an archive are skipped.
+func (srv *server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	if region == "" {
+		writeError(w, http.StatusBadRequest, "region is required")
+		return
+	}
+
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxHistoryDays {
+			writeError(w, http.StatusBadRequest, "days must be between 1 and "+strconv.Itoa(maxHistoryDays))
+			return
+		}
+		days = n
+	}
+
+	history, err := readHistory(srv.archive.read, srv.archiveLayout, srv.outputDir, region, days, time.Now())
+	if err != nil {
+		slog.Error("Reading archive failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "reading archive failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// readHistory reads region's cutoffs of the days days up to now from the
+// archive in outputDir through read, oldest first. Days without an archive or
+// without the region are skipped. Coarser layouts archive several days in the
+// same file, which is then reported once, under its most recent day.
+func readHistory(read func(path string) ([]byte, error), layout output.ArchiveLayout, outputDir, region string, days int, now time.Time) ([]historyPoint, error) {
+	today := now.In(layout.Location)
+	history := make([]historyPoint, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i)
+		filePath := layout.Path(outputDir, date)
+		if i > 0 && filePath == layout.Path(outputDir, date.AddDate(0, 0, 1)) {
+			continue
+		}
+		raw, err := read(filePath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive of %s: %w", date.Format("2006-01-02"), err)
+		}
+
+		var snapshot output.File
+		if err := json.Unmarshal(raw, &snapshot); err != nil {
+			slog.Error("Decoding archive failed", "date", date.Format("2006-01-02"), "error", err)
+			continue
+		}
+		if data, ok := snapshot.Regions[region]; ok {
+			history = append(history, historyPoint{Date: date.Format("2006-01-02"), Data: data})
+		}
+	}
+	return history, nil
+}
+
+// handleRecentHistory serves the snapshots retained in memory, oldest first.
+func (srv *server) handleRecentHistory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, srv.store.recent())
+}
+
+// handleRefresh runs an immediate cycle and reports the outcome per region.
+// When a refresh token is configured the request must present it as a bearer
+// token.
+func (srv *server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if srv.refreshToken != "" && !hasBearerToken(r, srv.refreshToken) {
+		writeError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+
+	report, err := srv.updater.refresh(srv.ctx, r.Context())
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, report)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleDebug serves the league and ladder sizes behind every region's
+// cutoffs, keyed by region and queue. It is only registered when a debug
+// token is configured, and requires that token.
+func (srv *server) handleDebug(w http.ResponseWriter, r *http.Request) {
+	if !hasBearerToken(r, srv.debugToken) {
+		writeError(w, http.StatusUnauthorized, "invalid debug token")
+		return
+	}
+
+	data, _ := srv.store.get()
+	if data == nil {
+		writeError(w, http.StatusServiceUnavailable, "cutoffs not computed yet")
+		return
+	}
+	ladders := make(map[string]map[string]cutoff.LadderInfo, len(data))
+	for region, regionData := range data {
+		queues := make(map[string]cutoff.LadderInfo)
+		if regionData.RANKED_SOLO_5x5.Computed() {
+			queues[cutoff.QueueSoloDuo] = regionData.RANKED_SOLO_5x5.Ladder
+		}
+		if regionData.RANKED_FLEX_SR.Computed() {
+			queues[cutoff.QueueFlex] = regionData.RANKED_FLEX_SR.Ladder
+		}
+		ladders[region] = queues
+	}
+	writeJSON(w, http.StatusOK, ladders)
+}
+
+// handleDebugLatency serves the region and fetch latencies of the latest
+// cycle, slowest first. Like /debug it requires the debug token.
+func (srv *server) handleDebugLatency(w http.ResponseWriter, r *http.Request) {
+	if !hasBearerToken(r, srv.debugToken) {
+		writeError(w, http.StatusUnauthorized, "invalid debug token")
+		return
+	}
+	writeJSON(w, http.StatusOK, srv.updater.latencies())
+}
+
+func (srv *server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentBuildInfo())
+}
+
+// hasBearerToken reports whether r carries token as its bearer token.
+func hasBearerToken(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Encoding response failed", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// testServer returns a server whose store holds outputData, with the
+// options applied to it.
+func testServer(outputData map[string]cutoff.RegionData, options ...func(*server)) *server {
+	st := newStore(10)
+	if outputData != nil {
+		st.set(outputData)
+	}
+	srv := &server{ctx: context.Background(), store: st, archive: newArchiveCache()}
+	for _, option := range options {
+		option(srv)
+	}
+	return srv
+}
+
+// serve sends a request to the server's routes and returns the response.
+func serve(srv *server, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    []string
+		method     string
+		origin     string
+		wantOrigin string
+		wantStatus int
+	}{
+		{"any origin", []string{"*"}, http.MethodGet, "https://example.com", "*", http.StatusOK},
+		{"listed origin", []string{"https://a.example", "https://example.com"}, http.MethodGet, "https://example.com", "https://example.com", http.StatusOK},
+		{"unlisted origin", []string{"https://a.example"}, http.MethodGet, "https://example.com", "", http.StatusOK},
+		{"preflight", []string{"https://example.com"}, http.MethodOptions, "https://example.com", "https://example.com", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testServer(map[string]cutoff.RegionData{"euw1": regionData(900, 400)}, func(srv *server) {
+				srv.allowedOrigins = tt.allowed
+			})
+			w := serve(srv, tt.method, "/cutoffs", http.Header{"Origin": {tt.origin}})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			h := w.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantOrigin == "" {
+				return
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != "GET, OPTIONS" {
+				t.Errorf("Access-Control-Allow-Methods = %q", got)
+			}
+			if got := h.Get("Access-Control-Allow-Headers"); got != "Content-Type" {
+				t.Errorf("Access-Control-Allow-Headers = %q", got)
+			}
+			if varies := slices.Contains(h.Values("Vary"), "Origin"); varies != (tt.wantOrigin != "*") {
+				t.Errorf("Vary = %q, want Origin only for a listed origin", h.Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestCORSSkipsPrivateEndpoints(t *testing.T) {
+	srv := testServer(nil, func(srv *server) { srv.allowedOrigins = []string{"*"} })
+	w := serve(srv, http.MethodGet, "/version", http.Header{"Origin": {"https://example.com"}})
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("/version Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
+
+// ladderServer returns a server retaining euw1's solo/duo ladder of five
+// players with 1000, 900, 800, 700 and 600 LP; its flex ladder is empty.
+func ladderServer() *server {
+	data := regionData(900, 700)
+	for i := range 5 {
+		data.RANKED_SOLO_5x5.Entries = append(data.RANKED_SOLO_5x5.Entries, cutoff.LeagueEntry{
+			PUUID:        strconv.Itoa(i),
+			LeaguePoints: 1000 - 100*i,
+		})
+	}
+	return testServer(map[string]cutoff.RegionData{"euw1": data}, func(srv *server) {
+		srv.retainLadder = true
+		srv.serveLadder = true
+	})
+}
+
+func TestCustomCutoff(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantLP     float64
+	}{
+		{"n=1", http.StatusOK, 1000},
+		{"n=3", http.StatusOK, 800},
+		{"n=5", http.StatusOK, 600},
+		{"n=6", http.StatusNotFound, 0},
+		{"n=0", http.StatusBadRequest, 0},
+		{"n=-1", http.StatusBadRequest, 0},
+		{"n=abc", http.StatusBadRequest, 0},
+		{"", http.StatusBadRequest, 0},
+		{"n=1&percentile=10", http.StatusBadRequest, 0},
+		{"percentile=100", http.StatusOK, 600},
+		{"percentile=1", http.StatusOK, 1000},
+		{"percentile=50", http.StatusOK, 900},
+		{"percentile=50&interpolate=true", http.StatusOK, 850},
+		{"percentile=0", http.StatusBadRequest, 0},
+		{"percentile=101", http.StatusBadRequest, 0},
+		{"n=1&queue=RANKED_FLEX_SR", http.StatusNotFound, 0},
+		{"n=1&queue=RANKED_TFT", http.StatusBadRequest, 0},
+	}
+	srv := ladderServer()
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(srv, http.MethodGet, "/cutoffs/euw1/custom?"+tt.query, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var got customCutoff
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.LP != tt.wantLP || got.LadderSize != 5 {
+				t.Errorf("LP = %g of a ladder of %d, want %g of 5", got.LP, got.LadderSize, tt.wantLP)
+			}
+		})
+	}
+}
+
+func TestCustomCutoffUnknownRegion(t *testing.T) {
+	if w := serve(ladderServer(), http.MethodGet, "/cutoffs/kr/custom?n=1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}
+
+func TestCutoffsSchemaVersion(t *testing.T) {
+	srv := testServer(map[string]cutoff.RegionData{"euw1": regionData(900, 400)})
+	w := serve(srv, http.MethodGet, "/cutoffs", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(raw["schemaVersion"]); got != strconv.Itoa(output.SchemaVersion) {
+		t.Errorf("schemaVersion = %s, want %d", got, output.SchemaVersion)
+	}
+	var file output.File
+	if err := json.Unmarshal(w.Body.Bytes(), &file); err != nil {
+		t.Fatal(err)
+	}
+	if got := file.Regions["euw1"].RANKED_SOLO_5x5.Challenger; len(file.Regions) != 1 || got != 900 {
+		t.Errorf("regions = %+v, want euw1 with its cutoffs", file.Regions)
+	}
+}
+
+func TestCutoffsMaxAge(t *testing.T) {
+	tests := []struct {
+		name       string
+		age        time.Duration
+		token      string
+		delay      time.Duration
+		target     string
+		wantStatus int
+		wantFetch  bool
+	}{
+		{"fresh", time.Second, "", 0, "/cutoffs?max_age=60", http.StatusOK, false},
+		{"stale then refreshed", time.Hour, "secret", 0, "/cutoffs?max_age=60", http.StatusOK, true},
+		{"stale without the refresh token", time.Hour, "", 0, "/cutoffs?max_age=60", http.StatusUnauthorized, false},
+		{"refresh times out", time.Hour, "secret", time.Second, "/cutoffs?max_age=60", http.StatusServiceUnavailable, true},
+		{"below the poll interval floor", time.Hour, "secret", 0, "/cutoffs?max_age=5", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := newFakeFetcher(map[string]int{"euw1": 1500})
+			fetcher.delays["euw1"] = tt.delay
+			u := testUpdater(t, fetcher, slotsYAML("euw1"))
+			u.store.setAt(map[string]cutoff.RegionData{"euw1": regionData(900, 400)}, time.Now().Add(-tt.age))
+			srv := testServer(nil, func(srv *server) {
+				srv.store, srv.updater = u.store, u
+				srv.refreshToken = "secret"
+				srv.freshnessWait = 100 * time.Millisecond
+			})
+
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := serve(srv, http.MethodGet, tt.target, header)
+			fetched := fetcher.callsTo("euw1") > 0
+			if tt.delay > 0 {
+				// Join the refresh the request gave up on, so it's done
+				// writing before the temp dir is removed.
+				u.refresh(context.Background(), context.Background())
+			}
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if fetched != tt.wantFetch {
+				t.Errorf("fetched = %t, want %t", fetched, tt.wantFetch)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var body output.File
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			want := 900
+			if tt.wantFetch {
+				want = 1490
+			}
+			if got := body.Regions["euw1"].RANKED_SOLO_5x5.Challenger; got != want {
+				t.Errorf("Challenger cutoff = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestRefreshCanceledOnShutdown(t *testing.T) {
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500})
+	fetcher.delays["euw1"] = time.Minute
+	u := testUpdater(t, fetcher, slotsYAML("euw1"))
+	ctx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	srv := testServer(nil, func(srv *server) {
+		srv.ctx, srv.store, srv.updater = ctx, u.store, u
+	})
+
+	done := make(chan struct{})
+	go func() {
+		serve(srv, http.MethodPost, "/refresh", nil)
+		close(done)
+	}()
+	for deadline := time.Now().Add(5 * time.Second); fetcher.callsTo("euw1") == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the refresh never fetched")
+		}
+	}
+
+	// The regular loop waiting for the refresh's cycle gives up with its
+	// context.
+	loopCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := u.runCycle(loopCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("runCycle during a refresh = %v, want context.DeadlineExceeded", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("runCycle waited %s for the refresh, want it to give up with its context", waited)
+	}
+
+	shutdown()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("refresh still running 2s after shutdown")
+	}
+}
+
+func TestLadderEndpoint(t *testing.T) {
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500})
+	u := testUpdater(t, fetcher, slotsYAML("euw1"), "SERVE_LADDER", "true")
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	srv := testServer(nil, func(srv *server) {
+		srv.store = u.store
+		srv.serveLadder = true
+	})
+
+	tests := []struct {
+		target      string
+		wantStatus  int
+		wantLP      []int
+		wantEntries int
+	}{
+		{"/ladder/euw1/RANKED_SOLO_5x5", http.StatusOK, []int{1500, 1490, 1480, 1400, 1390, 1380, 1370, 1200, 1190, 1180, 1170, 1160}, 0},
+		{"/ladder/euw1/RANKED_SOLO_5x5?limit=5", http.StatusOK, []int{1500, 1490, 1480, 1400, 1390}, 0},
+		{"/ladder/euw1/RANKED_FLEX_SR?limit=100", http.StatusOK, []int{1500, 1490, 1480, 1400, 1390, 1380, 1370, 1200, 1190, 1180, 1170, 1160}, 0},
+		{"/ladder/euw1/RANKED_SOLO_5x5?limit=2&entries=true", http.StatusOK, nil, 2},
+		{"/ladder/euw1/RANKED_SOLO_5x5?limit=0", http.StatusBadRequest, nil, 0},
+		{"/ladder/euw1/RANKED_SOLO_5x5?limit=x", http.StatusBadRequest, nil, 0},
+		{"/ladder/euw1/RANKED_SOLO_5x5?entries=maybe", http.StatusBadRequest, nil, 0},
+		{"/ladder/euw1/RANKED_TFT", http.StatusBadRequest, nil, 0},
+		{"/ladder/kr/RANKED_SOLO_5x5", http.StatusNotFound, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(srv, http.MethodGet, tt.target, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var body ladderResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Size != 12 {
+				t.Errorf("size = %d, want the whole ladder's 12", body.Size)
+			}
+			if !slices.Equal(body.LP, tt.wantLP) {
+				t.Errorf("LP = %v, want %v", body.LP, tt.wantLP)
+			}
+			if len(body.Entries) != tt.wantEntries {
+				t.Errorf("entries = %+v, want %d", body.Entries, tt.wantEntries)
+			}
+			for i := 1; i < len(body.Entries); i++ {
+				if body.Entries[i].LeaguePoints > body.Entries[i-1].LeaguePoints {
+					t.Errorf("entries aren't sorted by LP: %+v", body.Entries)
+				}
+			}
+		})
+	}
+}
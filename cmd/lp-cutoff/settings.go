@@ -0,0 +1,564 @@
+package main
+
+import (
+	"cmp"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+// settings holds the runtime options read from the environment.
+type settings struct {
+	APIKey string
+	// ConfigPath is the cutoffs config file to load instead of the embedded
+	// cutoffs.yaml, see configPathFromEnv.
+	ConfigPath string
+	// StrictConfig fails on any invalid config region instead of skipping
+	// it.
+	StrictConfig   bool
+	Regions        []string
+	OutputDir      string
+	Archive        output.ArchiveLayout
+	UserAgent      string
+	RiotBaseURL    string
+	RiotProxyURL   *url.URL
+	HTTPAddr       string
+	AllowedOrigins []string
+	ServeFiles     bool
+	// ServeDashboard serves an HTML overview of the current cutoffs at /.
+	ServeDashboard bool
+	RefreshToken   string
+	DebugToken     string
+	GRPCAddr       string
+	DBPath         string
+	MaxConcurrency int
+	// HistoryDepth is how many cycles' snapshots are kept in memory.
+	HistoryDepth int
+	// RegionRateLimit is the requests per second sent to each platform,
+	// in bursts of up to RegionRateBurst. Zero disables the limit.
+	RegionRateLimit float64
+	RegionRateBurst int
+	// LeaguePriority ranks the leagues for the rate limit, see
+	// riot.RegionLimiter.
+	LeaguePriority map[string]int
+	PaginatedFetch bool
+	RegionTimeout  time.Duration
+	RiotTimeouts   riot.Timeouts
+	// RetryBudget is how many transient fetch failures a cycle retries in
+	// total, each fetch at most MaxRetries times. Zero disables retries.
+	RetryBudget       int
+	MaxRetries        int
+	RetryBackoff      time.Duration
+	CycleTimeout      time.Duration
+	RegionStartJitter time.Duration
+	// MaxFailedCycles is how many consecutive cycles in which every region
+	// fails make the process exit. Zero disables the check.
+	MaxFailedCycles int
+
+	// PollInterval is the time between cycles set by POLL_INTERVAL or
+	// -interval, zero to leave it to the config file, see
+	// updater.pollInterval.
+	PollInterval time.Duration
+	// AdaptivePoll, set by ADAPTIVE_POLL, scales the poll interval with the
+	// rate-limit budget left, within [MinPollInterval, MaxPollInterval]. It is
+	// off unless asked for, so a configured interval is kept as is.
+	AdaptivePoll    bool
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+
+	LogLevel      string
+	LogFormat     string
+	RetentionDays int
+	DryRun        bool
+	SkipPreflight bool
+	WriteChanges  bool
+	WriteChurn    bool
+	WriteStatus   bool
+	WriteGzip     bool
+	GzipLevel     int
+	WriteCSV      bool
+	WriteFlat     bool
+	Minify        bool
+	DedupeArchive bool
+
+	MinLadderSize         int
+	ProvisionalSlotRatio  float64
+	HistogramBucketWidth  int
+	BoundaryWindow        int
+	CutoffGaps            bool
+	KeepLastOnProvisional bool
+	// MinPlausibleLP and MaxPlausibleLP bound the LP of the entries used,
+	// see cutoff.Options.
+	MinPlausibleLP int
+	MaxPlausibleLP int
+	// MinimalMode fetches and publishes only the solo/duo Challenger
+	// cutoffs.
+	MinimalMode bool
+	// RetainLadder keeps every queue's ladder in memory for the custom
+	// cutoff endpoint.
+	RetainLadder bool
+	// ServeLadder serves the full ladders under /ladder/, which implies
+	// retaining them.
+	ServeLadder bool
+	JumpGuard   jumpGuard
+	// MinCutoffChange is the largest move, in LP, for which a cutoff keeps
+	// its previously published value. Zero publishes every change.
+	MinCutoffChange int
+
+	S3Bucket   string
+	S3Endpoint string
+	S3Prefix   string
+
+	// Notifiers are the notification sinks from NOTIFIERS, plus a webhook
+	// for WEBHOOK_URL.
+	Notifiers       []notifierSpec
+	ChangeThreshold int
+	WebhookDebounce time.Duration
+}
+
+// configPathFromEnv returns the config file named by CUTOFFS_CONFIG, or by its
+// older alias CONFIG_PATH, empty to use the embedded cutoffs.yaml.
+func configPathFromEnv() string {
+	return cmp.Or(settingEnv("CUTOFFS_CONFIG"), settingEnv("CONFIG_PATH"))
+}
+
+func loadSettings() (settings, error) {
+	s := settings{
+		APIKey:         settingEnv("RIOT_API_KEY"),
+		ConfigPath:     configPathFromEnv(),
+		Regions:        splitList(settingEnv("REGIONS")),
+		OutputDir:      envString("OUTPUT_DIR", "cdn"),
+		UserAgent:      envString("USER_AGENT", defaultUserAgent()),
+		RiotBaseURL:    envString("RIOT_BASE_URL", riot.DefaultBaseURL),
+		HTTPAddr:       settingEnv("HTTP_ADDR"),
+		AllowedOrigins: splitList(envString("ALLOWED_ORIGINS", "*")),
+		RefreshToken:   settingEnv("REFRESH_TOKEN"),
+		DebugToken:     settingEnv("DEBUG_TOKEN"),
+		GRPCAddr:       settingEnv("GRPC_ADDR"),
+		DBPath:         settingEnv("DB_PATH"),
+		S3Bucket:       settingEnv("S3_BUCKET"),
+		S3Endpoint:     settingEnv("S3_ENDPOINT"),
+		S3Prefix:       settingEnv("S3_PREFIX"),
+		LogLevel:       settingEnv("LOG_LEVEL"),
+		LogFormat:      settingEnv("LOG_FORMAT"),
+	}
+
+	var err error
+	if s.APIKey, err = loadAPIKey(s.APIKey, settingEnv("RIOT_API_KEY_FILE")); err != nil {
+		return settings{}, err
+	}
+	if err := riot.ValidateBaseURL(s.RiotBaseURL); err != nil {
+		return settings{}, fmt.Errorf("invalid RIOT_BASE_URL: %w", err)
+	}
+	if s.RiotProxyURL, err = parseProxyURL(settingEnv("RIOT_PROXY_URL")); err != nil {
+		return settings{}, err
+	}
+	if s.StrictConfig, err = envBool("CONFIG_STRICT", false); err != nil {
+		return settings{}, err
+	}
+	if s.ServeFiles, err = envBool("SERVE_FILES", false); err != nil {
+		return settings{}, err
+	}
+	if s.ServeDashboard, err = envBool("SERVE_DASHBOARD", false); err != nil {
+		return settings{}, err
+	}
+	if s.MaxConcurrency, err = envInt("MAX_CONCURRENCY", 4); err != nil {
+		return settings{}, err
+	}
+	if s.MaxConcurrency < 1 {
+		return settings{}, fmt.Errorf("MAX_CONCURRENCY must be at least 1, got %d", s.MaxConcurrency)
+	}
+	if s.HistoryDepth, err = envInt("HISTORY_DEPTH", 2); err != nil {
+		return settings{}, err
+	}
+	if s.HistoryDepth < 2 || s.HistoryDepth > maxHistoryDepth {
+		return settings{}, fmt.Errorf("HISTORY_DEPTH must be between 2 and %d, got %d", maxHistoryDepth, s.HistoryDepth)
+	}
+	if s.RegionRateLimit, err = envFloat("REGION_RATE_LIMIT", 5); err != nil {
+		return settings{}, err
+	}
+	if s.RegionRateLimit < 0 {
+		return settings{}, fmt.Errorf("REGION_RATE_LIMIT must not be negative, got %g", s.RegionRateLimit)
+	}
+	if s.RegionRateBurst, err = envInt("REGION_RATE_BURST", 6); err != nil {
+		return settings{}, err
+	}
+	if s.RegionRateBurst < 1 {
+		return settings{}, fmt.Errorf("REGION_RATE_BURST must be at least 1, got %d", s.RegionRateBurst)
+	}
+	if s.LeaguePriority, err = riot.ParseLeaguePriority(splitList(envString("LEAGUE_PRIORITY", "challenger,grandmaster,master"))); err != nil {
+		return settings{}, fmt.Errorf("invalid LEAGUE_PRIORITY: %w", err)
+	}
+	if s.PaginatedFetch, err = envBool("PAGINATED_FETCH", false); err != nil {
+		return settings{}, err
+	}
+	if s.DryRun, err = envBool("DRY_RUN", false); err != nil {
+		return settings{}, err
+	}
+	if s.SkipPreflight, err = envBool("SKIP_PREFLIGHT", false); err != nil {
+		return settings{}, err
+	}
+	if s.WriteChanges, err = envBool("WRITE_CHANGES", false); err != nil {
+		return settings{}, err
+	}
+	if s.DedupeArchive, err = envBool("ARCHIVE_DEDUPE", false); err != nil {
+		return settings{}, err
+	}
+	if s.WriteStatus, err = envBool("WRITE_STATUS", false); err != nil {
+		return settings{}, err
+	}
+	if s.WriteChurn, err = envBool("WRITE_CHURN", false); err != nil {
+		return settings{}, err
+	}
+	if s.Notifiers, err = parseNotifiers(settingEnv("NOTIFIERS")); err != nil {
+		return settings{}, err
+	}
+	if webhookURL := settingEnv("WEBHOOK_URL"); webhookURL != "" {
+		s.Notifiers = append(s.Notifiers, notifierSpec{Kind: "webhook", Target: webhookURL})
+	}
+	if s.ChangeThreshold, err = envInt("CHANGE_THRESHOLD", 20); err != nil {
+		return settings{}, err
+	}
+	if s.ChangeThreshold < 0 {
+		return settings{}, fmt.Errorf("CHANGE_THRESHOLD must not be negative, got %d", s.ChangeThreshold)
+	}
+	if s.WebhookDebounce, err = envDuration("WEBHOOK_DEBOUNCE", 15*time.Minute); err != nil {
+		return settings{}, err
+	}
+	if s.WriteGzip, err = envBool("WRITE_GZIP", false); err != nil {
+		return settings{}, err
+	}
+	if s.GzipLevel, err = envInt("GZIP_LEVEL", gzip.DefaultCompression); err != nil {
+		return settings{}, err
+	}
+	if s.GzipLevel < gzip.HuffmanOnly || s.GzipLevel > gzip.BestCompression {
+		return settings{}, fmt.Errorf("GZIP_LEVEL must be between %d and %d, got %d", gzip.HuffmanOnly, gzip.BestCompression, s.GzipLevel)
+	}
+	if s.WriteCSV, s.WriteFlat, err = parseOutputFormats(envString("OUTPUT_FORMATS", "json")); err != nil {
+		return settings{}, err
+	}
+	if s.Minify, err = envBool("MINIFY", false); err != nil {
+		return settings{}, err
+	}
+	if s.MinLadderSize, err = envInt("MIN_LADDER_SIZE", 10); err != nil {
+		return settings{}, err
+	}
+	if s.ProvisionalSlotRatio, err = envFloat("PROVISIONAL_SLOT_RATIO", 1); err != nil {
+		return settings{}, err
+	}
+	if s.ProvisionalSlotRatio < 0 {
+		return settings{}, fmt.Errorf("PROVISIONAL_SLOT_RATIO must not be negative, got %g", s.ProvisionalSlotRatio)
+	}
+	if s.HistogramBucketWidth, err = envInt("HISTOGRAM_BUCKET_WIDTH", 0); err != nil {
+		return settings{}, err
+	}
+	if s.HistogramBucketWidth < 0 {
+		return settings{}, fmt.Errorf("HISTOGRAM_BUCKET_WIDTH must not be negative, got %d", s.HistogramBucketWidth)
+	}
+	if s.BoundaryWindow, err = envInt("BOUNDARY_WINDOW", 0); err != nil {
+		return settings{}, err
+	}
+	if s.BoundaryWindow < 0 {
+		return settings{}, fmt.Errorf("BOUNDARY_WINDOW must not be negative, got %d", s.BoundaryWindow)
+	}
+	if s.CutoffGaps, err = envBool("CUTOFF_GAPS", false); err != nil {
+		return settings{}, err
+	}
+	if s.MinimalMode, err = envBool("MINIMAL_MODE", false); err != nil {
+		return settings{}, err
+	}
+	if s.RetainLadder, err = envBool("RETAIN_LADDER", false); err != nil {
+		return settings{}, err
+	}
+	if s.ServeLadder, err = envBool("SERVE_LADDER", false); err != nil {
+		return settings{}, err
+	}
+	if s.MinPlausibleLP, err = envInt("MIN_PLAUSIBLE_LP", 0); err != nil {
+		return settings{}, err
+	}
+	if s.MaxPlausibleLP, err = envInt("MAX_PLAUSIBLE_LP", 5000); err != nil {
+		return settings{}, err
+	}
+	if s.MaxPlausibleLP < 0 {
+		return settings{}, fmt.Errorf("MAX_PLAUSIBLE_LP must not be negative, got %d", s.MaxPlausibleLP)
+	}
+	if s.MaxPlausibleLP > 0 && s.MinPlausibleLP > s.MaxPlausibleLP {
+		return settings{}, fmt.Errorf("MIN_PLAUSIBLE_LP (%d) must not exceed MAX_PLAUSIBLE_LP (%d)", s.MinPlausibleLP, s.MaxPlausibleLP)
+	}
+	if s.KeepLastOnProvisional, err = envBool("KEEP_LAST_ON_PROVISIONAL", false); err != nil {
+		return settings{}, err
+	}
+	if s.JumpGuard.MaxJumpPct, err = envFloat("MAX_JUMP_PCT", 50); err != nil {
+		return settings{}, err
+	}
+	if s.JumpGuard.MaxJumpPct < 0 {
+		return settings{}, fmt.Errorf("MAX_JUMP_PCT must not be negative, got %g", s.JumpGuard.MaxJumpPct)
+	}
+	if s.JumpGuard.ShrinkRatio, err = envFloat("JUMP_LADDER_SHRINK_RATIO", 0.5); err != nil {
+		return settings{}, err
+	}
+	if s.JumpGuard.ShrinkRatio < 0 || s.JumpGuard.ShrinkRatio > 1 {
+		return settings{}, fmt.Errorf("JUMP_LADDER_SHRINK_RATIO must be between 0 and 1, got %g", s.JumpGuard.ShrinkRatio)
+	}
+	if s.MinCutoffChange, err = envInt("MIN_CUTOFF_CHANGE", 0); err != nil {
+		return settings{}, err
+	}
+	if s.MinCutoffChange < 0 {
+		return settings{}, fmt.Errorf("MIN_CUTOFF_CHANGE must not be negative, got %d", s.MinCutoffChange)
+	}
+	if s.Archive, err = output.NewArchiveLayout(envString("ARCHIVE_LAYOUT", output.DefaultArchiveLayout), settingEnv("TZ")); err != nil {
+		return settings{}, err
+	}
+	if s.RetentionDays, err = envInt("RETENTION_DAYS", 90); err != nil {
+		return settings{}, err
+	}
+	if s.RetentionDays < 0 {
+		return settings{}, fmt.Errorf("RETENTION_DAYS must not be negative, got %d", s.RetentionDays)
+	}
+	if s.PollInterval, err = envDuration("POLL_INTERVAL", 0); err != nil {
+		return settings{}, err
+	}
+	if s.PollInterval != 0 && s.PollInterval < minPollIntervalFloor {
+		return settings{}, fmt.Errorf("POLL_INTERVAL must be at least %s, got %s", minPollIntervalFloor, s.PollInterval)
+	}
+	if s.AdaptivePoll, err = envBool("ADAPTIVE_POLL", false); err != nil {
+		return settings{}, err
+	}
+	if s.MinPollInterval, err = envDuration("MIN_POLL_INTERVAL", 30*time.Second); err != nil {
+		return settings{}, err
+	}
+	if s.MinPollInterval < minPollIntervalFloor {
+		return settings{}, fmt.Errorf("MIN_POLL_INTERVAL must be at least %s, got %s", minPollIntervalFloor, s.MinPollInterval)
+	}
+	if s.MaxPollInterval, err = envDuration("MAX_POLL_INTERVAL", 5*time.Minute); err != nil {
+		return settings{}, err
+	}
+	if s.MaxPollInterval < s.MinPollInterval {
+		return settings{}, fmt.Errorf("MAX_POLL_INTERVAL (%s) must not be below MIN_POLL_INTERVAL (%s)", s.MaxPollInterval, s.MinPollInterval)
+	}
+	if s.RegionTimeout, err = envDuration("REGION_TIMEOUT", 30*time.Second); err != nil {
+		return settings{}, err
+	}
+	if s.RegionTimeout <= 0 {
+		return settings{}, fmt.Errorf("REGION_TIMEOUT must be positive, got %s", s.RegionTimeout)
+	}
+	for _, timeout := range []struct {
+		name string
+		dst  *time.Duration
+		def  time.Duration
+	}{
+		{"RIOT_DIAL_TIMEOUT", &s.RiotTimeouts.Dial, 5 * time.Second},
+		{"RIOT_TLS_HANDSHAKE_TIMEOUT", &s.RiotTimeouts.TLSHandshake, 5 * time.Second},
+		{"RIOT_RESPONSE_HEADER_TIMEOUT", &s.RiotTimeouts.ResponseHeader, 10 * time.Second},
+	} {
+		if *timeout.dst, err = envDuration(timeout.name, timeout.def); err != nil {
+			return settings{}, err
+		}
+		if *timeout.dst <= 0 {
+			return settings{}, fmt.Errorf("%s must be positive, got %s", timeout.name, *timeout.dst)
+		}
+	}
+	if s.RetryBudget, err = envInt("RETRY_BUDGET", 10); err != nil {
+		return settings{}, err
+	}
+	if s.RetryBudget < 0 {
+		return settings{}, fmt.Errorf("RETRY_BUDGET must not be negative, got %d", s.RetryBudget)
+	}
+	if s.MaxRetries, err = envInt("MAX_RETRIES", 2); err != nil {
+		return settings{}, err
+	}
+	if s.MaxRetries < 0 {
+		return settings{}, fmt.Errorf("MAX_RETRIES must not be negative, got %d", s.MaxRetries)
+	}
+	if s.RetryBackoff, err = envDuration("RETRY_BACKOFF", time.Second); err != nil {
+		return settings{}, err
+	}
+	if s.RetryBackoff <= 0 {
+		return settings{}, fmt.Errorf("RETRY_BACKOFF must be positive, got %s", s.RetryBackoff)
+	}
+	if s.CycleTimeout, err = envDuration("CYCLE_TIMEOUT", 45*time.Second); err != nil {
+		return settings{}, err
+	}
+	if s.CycleTimeout <= 0 {
+		return settings{}, fmt.Errorf("CYCLE_TIMEOUT must be positive, got %s", s.CycleTimeout)
+	}
+	if s.RegionStartJitter, err = envDuration("REGION_START_JITTER", 5*time.Second); err != nil {
+		return settings{}, err
+	}
+	if s.RegionStartJitter < 0 || s.RegionStartJitter > s.MinPollInterval/2 {
+		return settings{}, fmt.Errorf("REGION_START_JITTER must be between 0 and half of MIN_POLL_INTERVAL (%s), got %s", s.MinPollInterval/2, s.RegionStartJitter)
+	}
+	if s.MaxFailedCycles, err = envInt("MAX_FAILED_CYCLES", 10); err != nil {
+		return settings{}, err
+	}
+	if s.MaxFailedCycles < 0 || s.MaxFailedCycles == 1 {
+		return settings{}, fmt.Errorf("MAX_FAILED_CYCLES must be 0 to disable it or at least 2, got %d", s.MaxFailedCycles)
+	}
+	return s, nil
+}
+
+func (s settings) outputOptions() output.Options {
+	return output.Options{
+		Dir:           s.OutputDir,
+		Archive:       s.Archive,
+		Gzip:          s.WriteGzip,
+		GzipLevel:     s.GzipLevel,
+		CSV:           s.WriteCSV,
+		Flat:          s.WriteFlat,
+		Minify:        s.Minify,
+		DedupeArchive: s.DedupeArchive,
+	}
+}
+
+// parseOutputFormats parses the comma-separated OUTPUT_FORMATS list and
+// reports whether CSV output and the flat JSON file are enabled. The nested
+// JSON is the canonical format and is always written.
+func parseOutputFormats(value string) (csvEnabled, flatEnabled bool, err error) {
+	for _, format := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(format)) {
+		case "json", "":
+		case "csv":
+			csvEnabled = true
+		case "flat":
+			flatEnabled = true
+		default:
+			return false, false, fmt.Errorf("unknown format %q in OUTPUT_FORMATS", format)
+		}
+	}
+	return csvEnabled, flatEnabled, nil
+}
+
+// parseProxyURL parses the RIOT_PROXY_URL value, returning nil when it is
+// empty.
+func parseProxyURL(value string) (*url.URL, error) {
+	if value == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("parse RIOT_PROXY_URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("RIOT_PROXY_URL must use http, https or socks5, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("RIOT_PROXY_URL must include a host")
+	}
+	return u, nil
+}
+
+// loadAPIKey resolves the Riot API key from RIOT_API_KEY and the file named by
+// RIOT_API_KEY_FILE, which is how Docker and Kubernetes mount secrets. When
+// both are set they must agree. Neither being set is left to requireAPIKey,
+// since only the commands that call Riot need a key.
+func loadAPIKey(envKey, keyFile string) (string, error) {
+	if keyFile == "" {
+		return envKey, nil
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("read RIOT_API_KEY_FILE: %w", err)
+	}
+	fileKey := strings.TrimSpace(string(data))
+	if fileKey == "" {
+		return "", fmt.Errorf("RIOT_API_KEY_FILE %s is empty", keyFile)
+	}
+	if envKey != "" && envKey != fileKey {
+		return "", errors.New("RIOT_API_KEY and RIOT_API_KEY_FILE are both set but differ")
+	}
+	return fileKey, nil
+}
+
+// requireAPIKey reports a missing Riot API key.
+func (s settings) requireAPIKey() error {
+	if s.APIKey == "" {
+		return errors.New("RIOT_API_KEY or RIOT_API_KEY_FILE environment variable is required")
+	}
+	return nil
+}
+
+// envInt parses the integer environment variable name, returning def when it
+// is unset.
+func envInt(name string, def int) (int, error) {
+	value := settingEnv(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// envFloat parses the float environment variable name, returning def when it
+// is unset.
+func envFloat(name string, def float64) (float64, error) {
+	value := settingEnv(name)
+	if value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// envDuration parses the duration environment variable name (e.g. "30s"),
+// returning def when it is unset.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	value := settingEnv(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// envString returns the environment variable name, or def when it is unset.
+func envString(name, def string) string {
+	if value := settingEnv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// envBool parses the boolean environment variable name, returning def when it
+// is unset.
+func envBool(name string, def bool) (bool, error) {
+	value := settingEnv(name)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// splitList splits a comma-separated environment value, dropping blanks.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
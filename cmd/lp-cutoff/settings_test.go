@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// regionData returns computed solo/duo and flex cutoffs.
+func regionData(challenger, grandmaster int) cutoff.RegionData {
+	now := time.Now().UTC()
+	cutoffs := cutoff.Cutoffs{Challenger: challenger, Grandmaster: grandmaster, UpdatedAt: now}
+	return cutoff.RegionData{RANKED_SOLO_5x5: cutoffs, RANKED_FLEX_SR: cutoffs, UpdatedAt: now}
+}
+
+func TestOutputDirFromEnvironment(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	t.Setenv("OUTPUT_DIR", dir)
+	s, err := loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.OutputDir != dir {
+		t.Fatalf("OutputDir = %q, want %q", s.OutputDir, dir)
+	}
+
+	if err := output.Write(s.outputOptions(), map[string]cutoff.RegionData{"euw1": regionData(900, 400)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		filepath.Join(dir, "current", "cutoffs.json"),
+		filepath.Join(dir, "current", "euw1", "cutoffs.json"),
+		s.Archive.Path(dir, time.Now()),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was not written: %v", path, err)
+		}
+	}
+}
+
+func TestSettingEnvAliases(t *testing.T) {
+	for alias, setting := range settingEnvAliases {
+		t.Run(alias, func(t *testing.T) {
+			t.Setenv(setting, "from-setting")
+			if got := settingEnv(setting); got != "from-setting" {
+				t.Errorf("settingEnv(%s) = %q, want %q", setting, got, "from-setting")
+			}
+			t.Setenv(alias, "from-alias")
+			if got := settingEnv(setting); got != "from-alias" {
+				t.Errorf("settingEnv(%s) with %s set = %q, want %q", setting, alias, got, "from-alias")
+			}
+
+			_, overrides, problems := applyEnvOverrides(nil, []string{alias + "=from-alias"})
+			if len(overrides) != 0 || len(problems) != 0 {
+				t.Errorf("applyEnvOverrides(%s) = %v, %v, want it left to the settings", alias, overrides, problems)
+			}
+		})
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	t.Setenv("OUTPUT_DIR", "cdn")
+	t.Setenv("LPCUTOFF_OUTPUT_DIR", dir)
+	s, err := loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.OutputDir != dir {
+		t.Errorf("OutputDir = %q, want the LPCUTOFF_OUTPUT_DIR alias %q", s.OutputDir, dir)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	s, err := loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UserAgent != defaultUserAgent() {
+		t.Errorf("default UserAgent = %q, want %q", s.UserAgent, defaultUserAgent())
+	}
+
+	t.Setenv("USER_AGENT", "my-app/1.0")
+	if s, err = loadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if s.UserAgent != "my-app/1.0" {
+		t.Errorf("UserAgent = %q, want the USER_AGENT override", s.UserAgent)
+	}
+}
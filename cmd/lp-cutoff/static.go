@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+// staticMaxAge is how long clients may cache files served from the output
+// directory; the current files change every cycle.
+const staticMaxAge = time.Minute
+
+// staticFiles serves the output directory as is, with ETag and Last-Modified
+// validators so conditional requests are answered with 304 Not Modified.
+// Files are opened through an os.Root, so neither ".." nor symlinks can reach
+// outside the directory.
+type staticFiles struct {
+	root   *os.Root
+	server http.Handler
+}
+
+func newStaticFiles(dir string) (*staticFiles, error) {
+	if err := output.EnsureDir(dir); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open output directory %s: %w", dir, err)
+	}
+	return &staticFiles{root: root, server: http.FileServerFS(root.FS())}, nil
+}
+
+func (sf *staticFiles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	if info, err := fs.Stat(sf.root.FS(), name); err == nil && !info.IsDir() {
+		// http.FileServer only sets Last-Modified; the ETag lets it
+		// also answer If-None-Match.
+		w.Header().Set("ETag", `"`+strconv.FormatInt(info.ModTime().UnixNano(), 36)+"-"+strconv.FormatInt(info.Size(), 36)+`"`)
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(staticMaxAge.Seconds())))
+	sf.server.ServeHTTP(w, r)
+}
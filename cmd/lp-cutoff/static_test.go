@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// staticTestServer serves a temp output directory holding
+// current/cutoffs.json, and a secret file next to it linked from
+// current/link.txt, under /files/.
+func staticTestServer(t *testing.T) *server {
+	t.Helper()
+	base := t.TempDir()
+	dir := filepath.Join(base, "cdn")
+	if err := os.MkdirAll(filepath.Join(dir, "current"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(dir, "current", "cutoffs.json"): `{"schemaVersion":1}`,
+		filepath.Join(base, "secret.txt"):             "secret",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(dir, "current", "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	files, err := newStaticFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testServer(nil, func(srv *server) { srv.files = files })
+}
+
+func TestStaticFilesConditionalRequests(t *testing.T) {
+	srv := staticTestServer(t)
+	first := serve(srv, http.MethodGet, "/files/current/cutoffs.json", nil)
+	if first.Code != http.StatusOK || first.Body.String() != `{"schemaVersion":1}` {
+		t.Fatalf("status %d, body %q", first.Code, first.Body)
+	}
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("ETag %q, Last-Modified %q, want both set", etag, lastModified)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	for name, header := range map[string]http.Header{
+		"If-None-Match":     {"If-None-Match": {etag}},
+		"If-Modified-Since": {"If-Modified-Since": {lastModified}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if w := serve(srv, http.MethodGet, "/files/current/cutoffs.json", header); w.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want 304", w.Code)
+			}
+		})
+	}
+	t.Run("changed ETag", func(t *testing.T) {
+		header := http.Header{"If-None-Match": {`"stale"`}}
+		if w := serve(srv, http.MethodGet, "/files/current/cutoffs.json", header); w.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", w.Code)
+		}
+	})
+}
+
+func TestStaticFilesStayInOutputDir(t *testing.T) {
+	srv := staticTestServer(t)
+	for _, target := range []string{"/files/../secret.txt", "/files/%2e%2e/secret.txt", "/files/current/../../secret.txt", "/files/current/link.txt"} {
+		w := serve(srv, http.MethodGet, target, nil)
+		if w.Code == http.StatusOK && w.Body.String() == "secret" {
+			t.Errorf("%s served the file outside the output directory", target)
+		}
+	}
+}
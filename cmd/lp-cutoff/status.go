@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+)
+
+type queueStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// regionQueueStatus reports the queues of a region's result: the computed
+// queues of data on success, the queues that failed otherwise.
+func regionQueueStatus(data cutoff.RegionData, err error, apiKey string) map[string]queueStatus {
+	queues := make(map[string]queueStatus)
+	if err != nil {
+		for _, queueErr := range cutoff.QueueErrors(err) {
+			queues[queueErr.Queue] = queueStatus{Error: sanitizeError(queueErr.Err, apiKey)}
+		}
+	} else {
+		for _, value := range data.Values() {
+			queues[value.Queue] = queueStatus{OK: true}
+		}
+	}
+	if len(queues) == 0 {
+		return nil
+	}
+	return queues
+}
+
+// sanitizeError renders err for publication: the API key is redacted, and
+// the response body snippets Riot errors carry are dropped.
+func sanitizeError(err error, apiKey string) string {
+	msg := err.Error()
+	if apiKey != "" {
+		msg = strings.ReplaceAll(msg, apiKey, "[redacted]")
+	}
+	lines := strings.Split(msg, "\n")
+	for i, line := range lines {
+		line, _, _ = strings.Cut(line, " - body: ")
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeStatusFile writes the cycle's report to current/status.json.
+func writeStatusFile(outputDir string, report cycleReport) error {
+	jsonData, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshal status JSON: %w", err)
+	}
+
+	currentDir := filepath.Join(outputDir, "current")
+	if err := output.EnsureDir(currentDir); err != nil {
+		return err
+	}
+	return output.WriteFile(filepath.Join(currentDir, "status.json"), jsonData)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+func TestStatusFileReportsFailedFetches(t *testing.T) {
+	const apiKey = "RGAPI-secret"
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+	fetcher.set("kr", 1800, fmt.Errorf("GET https://kr.api.riotgames.com/?api_key=%s: status 503 - body: <html>maintenance</html>", apiKey))
+	u := testUpdater(t, fetcher, slotsYAML("euw1")+slotsYAML("kr"), "WRITE_STATUS", "true", "RIOT_API_KEY", apiKey)
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(u.settings.OutputDir, "current", "status.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), apiKey) || strings.Contains(string(raw), "maintenance") {
+		t.Errorf("status file leaks the API key or the body: %s", raw)
+	}
+	var report cycleReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if euw1 := report.Regions["euw1"]; !euw1.OK || !euw1.Queues[cutoff.QueueSoloDuo].OK || !euw1.Queues[cutoff.QueueFlex].OK {
+		t.Errorf("euw1 status = %+v, want it and both queues OK", euw1)
+	}
+	kr := report.Regions["kr"]
+	if kr.OK || !strings.Contains(kr.Error, "[redacted]") {
+		t.Errorf("kr status = %+v, want it failed with the key redacted", kr)
+	}
+	for _, queue := range []string{cutoff.QueueSoloDuo, cutoff.QueueFlex} {
+		if status := kr.Queues[queue]; status.OK || !strings.Contains(status.Error, "status 503") {
+			t.Errorf("kr %s status = %+v, want the failed fetch", queue, status)
+		}
+	}
+}
+
+func TestSanitizeError(t *testing.T) {
+	tests := []struct {
+		name, err, want string
+	}{
+		{"key redacted", "GET /?api_key=k3y failed", "GET /?api_key=[redacted] failed"},
+		{"body dropped", "status 500 - body: <html>", "status 500"},
+		{"every line cleaned", "a - body: x\nb k3y - body: y", "a\nb [redacted]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeError(fmt.Errorf("%s", tt.err), "k3y"); got != tt.want {
+				t.Errorf("sanitizeError = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// maxHistoryDepth caps how many snapshots the store retains.
+const maxHistoryDepth = 100
+
+// store holds the latest published cutoffs so they can be served without
+// touching the filesystem, along with the snapshots published before them.
+type store struct {
+	mu sync.RWMutex
+	// history holds the most recent snapshots, oldest first; the last one is
+	// the current snapshot.
+	history []snapshot
+	depth   int
+}
+
+// snapshot is the output of one cycle.
+type snapshot struct {
+	UpdatedAt time.Time                    `json:"updatedAt"`
+	Data      map[string]cutoff.RegionData `json:"data"`
+}
+
+// newStore returns a store retaining the latest depth snapshots, at least the
+// current one.
+func newStore(depth int) *store {
+	return &store{depth: max(depth, 1)}
+}
+
+// set replaces the snapshot with data, evicting the oldest snapshot once
+// depth are retained. The map must not be modified by the caller afterwards.
+func (s *store) set(data map[string]cutoff.RegionData) {
+	s.setAt(data, time.Now())
+}
+
+// setAt is set for data that was computed at updatedAt.
+func (s *store) setAt(data map[string]cutoff.RegionData, updatedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.history) == s.depth {
+		copy(s.history, s.history[1:])
+		s.history = s.history[:len(s.history)-1]
+	}
+	s.history = append(s.history, snapshot{UpdatedAt: updatedAt.UTC(), Data: data})
+}
+
+// get returns the current snapshot and when it was last replaced. The returned
+// map must not be modified.
+func (s *store) get() (map[string]cutoff.RegionData, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.history) == 0 {
+		return nil, time.Time{}
+	}
+	latest := s.history[len(s.history)-1]
+	return latest.Data, latest.UpdatedAt
+}
+
+// previous returns the snapshot replaced by the current one, or nil when
+// there is none or only the current one is retained. The returned map must
+// not be modified.
+func (s *store) previous() map[string]cutoff.RegionData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.history) < 2 {
+		return nil
+	}
+	return s.history[len(s.history)-2].Data
+}
+
+// recent returns the retained snapshots, oldest first. Their maps must not be
+// modified.
+func (s *store) recent() []snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]snapshot(nil), s.history...)
+}
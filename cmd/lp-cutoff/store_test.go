@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// challengers returns the euw1 solo/duo Challenger cutoff of every snapshot.
+func challengers(snapshots []snapshot) []int {
+	var lps []int
+	for _, s := range snapshots {
+		lps = append(lps, s.Data["euw1"].RANKED_SOLO_5x5.Challenger)
+	}
+	return lps
+}
+
+func TestStoreRetainsDepth(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  []int
+	}{
+		{depth: 0, want: []int{5}},
+		{depth: 1, want: []int{5}},
+		{depth: 3, want: []int{3, 4, 5}},
+		{depth: 10, want: []int{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		st := newStore(tt.depth)
+		if data, _ := st.get(); data != nil || st.previous() != nil {
+			t.Fatalf("depth %d: empty store has snapshots", tt.depth)
+		}
+		for lp := 1; lp <= 5; lp++ {
+			st.set(map[string]cutoff.RegionData{"euw1": regionData(lp, 0)})
+		}
+		if got := challengers(st.recent()); !slices.Equal(got, tt.want) {
+			t.Errorf("depth %d: retained %v, want %v", tt.depth, got, tt.want)
+		}
+		if current, _ := st.get(); current["euw1"].RANKED_SOLO_5x5.Challenger != 5 {
+			t.Errorf("depth %d: current = %v, want the latest", tt.depth, current)
+		}
+		wantPrevious := 4
+		if tt.depth <= 1 {
+			wantPrevious = 0
+		}
+		if got := st.previous()["euw1"].RANKED_SOLO_5x5.Challenger; got != wantPrevious {
+			t.Errorf("depth %d: previous Challenger = %d, want %d", tt.depth, got, wantPrevious)
+		}
+	}
+}
+
+func TestRecentHistoryEndpoint(t *testing.T) {
+	srv := testServer(nil)
+	srv.store = newStore(2)
+	for lp := 1; lp <= 3; lp++ {
+		srv.store.set(map[string]cutoff.RegionData{"euw1": regionData(lp, 0)})
+	}
+	w := serve(srv, http.MethodGet, "/history/recent", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var snapshots []snapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &snapshots); err != nil {
+		t.Fatal(err)
+	}
+	if got := challengers(snapshots); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("recent history = %v, want [2 3]", got)
+	}
+}
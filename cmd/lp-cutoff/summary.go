@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// tierSummary aggregates one queue and tier's cutoff across all regions.
+type tierSummary struct {
+	Queue         string  `json:"queue"`
+	Tier          string  `json:"tier"`
+	Min           int     `json:"min"`
+	Max           int     `json:"max"`
+	Average       float64 `json:"average"`
+	HighestRegion string  `json:"highestRegion"`
+	LowestRegion  string  `json:"lowestRegion"`
+	Regions       int     `json:"regions"`
+}
+
+// summarizeCutoffs aggregates the cutoffs of every region per queue and tier,
+// ordered by queue then tier. Ties for the highest or lowest cutoff go to the
+// alphabetically first region.
+func summarizeCutoffs(data map[string]cutoff.RegionData) []tierSummary {
+	regions := make([]string, 0, len(data))
+	for region := range data {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	var summaries []tierSummary
+	index := make(map[string]int)
+	totals := make(map[string]int)
+	for _, region := range regions {
+		for _, value := range data[region].Values() {
+			key := value.Queue + "_" + value.Tier
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(summaries)
+				summaries = append(summaries, tierSummary{
+					Queue:         value.Queue,
+					Tier:          value.Tier,
+					Min:           value.LP,
+					Max:           value.LP,
+					HighestRegion: region,
+					LowestRegion:  region,
+				})
+				i = index[key]
+			}
+
+			summary := &summaries[i]
+			if value.LP > summary.Max {
+				summary.Max, summary.HighestRegion = value.LP, region
+			}
+			if value.LP < summary.Min {
+				summary.Min, summary.LowestRegion = value.LP, region
+			}
+			summary.Regions++
+			totals[key] += value.LP
+		}
+	}
+
+	for i := range summaries {
+		summary := &summaries[i]
+		summary.Average = float64(totals[summary.Queue+"_"+summary.Tier]) / float64(summary.Regions)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Queue != summaries[j].Queue {
+			return summaries[i].Queue < summaries[j].Queue
+		}
+		return summaries[i].Tier < summaries[j].Tier
+	})
+	return summaries
+}
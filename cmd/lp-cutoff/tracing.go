@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// tracer creates the cycle, region and fetch spans. It goes through the
+// global tracer provider, which is a no-op until setupTracing installs an
+// exporting one.
+var tracer = otel.Tracer("github.com/renja-g/lol-lp-cutoff")
+
+// setupTracing exports traces over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT
+// is set; the exporter reads the rest of its configuration from the standard
+// OTEL_* variables. Without an endpoint tracing stays a no-op. The returned
+// function flushes and stops the exporter.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP trace exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("league-lp-cutoff"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// endSpan records the outcome and latency of the work span covered and ends
+// it.
+func endSpan(span trace.Span, start time.Time, err error) {
+	span.SetAttributes(attribute.Int64("latency_ms", time.Since(start).Milliseconds()))
+	if err != nil {
+		span.SetAttributes(attribute.String("status", "error"))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.String("status", "ok"))
+	}
+	span.End()
+}
+
+// tracedFetcher wraps every fetch it passes on in a fetchLeagueData span.
+type tracedFetcher struct {
+	cutoff.Fetcher
+}
+
+func (f tracedFetcher) Fetch(ctx context.Context, region, league, queueType string) (cutoff.LeagueResponse, error) {
+	ctx, span := tracer.Start(ctx, "fetchLeagueData", trace.WithAttributes(
+		attribute.String("region", region),
+		attribute.String("queue", queueType),
+		attribute.String("tier", league),
+	))
+	start := time.Now()
+	resp, err := f.Fetcher.Fetch(ctx, region, league, queueType)
+	endSpan(span, start, err)
+	return resp, err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttribute returns the value of the attribute key of span.
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) string {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestCycleSpanTree(t *testing.T) {
+	// tracer delegates to the first provider installed globally, so this is
+	// the only test that may install one.
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+	fetcher.set("kr", 1800, errors.New("riot unavailable"))
+	u := testUpdater(t, fetcher, slotsYAML("euw1")+slotsYAML("kr"))
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	byName := make(map[string][]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	if len(byName["cycle"]) != 1 {
+		t.Fatalf("cycle spans = %d, want 1 among %d spans", len(byName["cycle"]), len(spans))
+	}
+	cycle := byName["cycle"][0]
+	if cycle.Parent.IsValid() {
+		t.Errorf("cycle span has parent %s, want it to be the root", cycle.Parent.SpanID())
+	}
+
+	regions := make(map[string]tracetest.SpanStub)
+	for _, span := range byName["processRegion"] {
+		if span.Parent.SpanID() != cycle.SpanContext.SpanID() {
+			t.Errorf("processRegion span of %s isn't a child of the cycle span", spanAttribute(span, "region"))
+		}
+		regions[span.SpanContext.SpanID().String()] = span
+	}
+	if len(regions) != 2 {
+		t.Fatalf("processRegion spans = %d, want one per region", len(regions))
+	}
+
+	fetches := make(map[string]int)
+	for _, span := range byName["fetchLeagueData"] {
+		parent, ok := regions[span.Parent.SpanID().String()]
+		region := spanAttribute(span, "region")
+		if !ok || spanAttribute(parent, "region") != region {
+			t.Errorf("fetchLeagueData span of %s isn't a child of its processRegion span", region)
+		}
+		fetches[region]++
+		failed := region == "kr"
+		if (span.Status.Code == codes.Error) != failed {
+			t.Errorf("%s fetch span status = %v, want an error only for kr", region, span.Status.Code)
+		}
+		if got, want := spanAttribute(span, "status"), map[bool]string{false: "ok", true: "error"}[failed]; got != want {
+			t.Errorf("%s fetch span status attribute = %q, want %q", region, got, want)
+		}
+	}
+	if fetches["euw1"] != 6 || fetches["kr"] == 0 {
+		t.Errorf("fetch spans per region = %v, want 6 for euw1 and kr's failed fetches", fetches)
+	}
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/output"
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+// updater runs the fetch-compute-publish cycles and carries the state kept
+// between them.
+type updater struct {
+	settings settings
+	watcher  *configWatcher
+	fetcher  cutoff.Fetcher
+	opts     cutoff.Options
+	store    *store
+	notifier *changeNotifier
+	uploader *s3Uploader
+	db       *cutoffDB
+
+	// lastGood keeps the most recent successful result of every region so a
+	// region that fails in one cycle is republished as stale instead of
+	// disappearing from the output.
+	lastGood   map[string]cutoff.RegionData
+	lastPruned string
+	// fetchedAt holds the start of the cycle that last computed each region,
+	// to poll regions with their own poll_interval only when they are due.
+	// Forced refreshes and regions whose config changed ignore it.
+	fetchedAt map[string]time.Time
+	// configInterval is the poll interval of the config the latest cycle
+	// ran with.
+	configInterval atomic.Int64
+	// computed keeps every region's latest ComputeRegion result with the
+	// config it was computed with, so queues whose leagues didn't change can
+	// reuse their cutoffs.
+	computed map[string]computedRegion
+	// extremes tracks the cutoffs' watermarks of the current archive period.
+	extremes extremesTracker
+	// churn holds the tier promotions and demotions of the latest cycle when
+	// players are tracked.
+	churn []tierChurn
+
+	// scheduleMu guards the wake-up schedule of the poll loop. globalDue is
+	// when the regions without their own poll_interval are next due, zero
+	// before the first cycle; globalFetched records that a cycle fetched
+	// them since nextInterval last moved globalDue. regionsDue is the
+	// earliest time a region with its own poll_interval is due, zero when
+	// there is none.
+	scheduleMu    sync.Mutex
+	globalDue     time.Time
+	globalFetched bool
+	regionsDue    time.Time
+
+	// latencyMu guards latency, the latencies of the latest cycle, which the
+	// debug endpoint reads while cycles run.
+	latencyMu sync.Mutex
+	latency   latencyReport
+
+	// cycleSem serializes cycles so refreshes never overlap the regular
+	// loop. It is a semaphore rather than a mutex so a cycle waiting for its
+	// turn gives up when its context is canceled.
+	cycleSem chan struct{}
+	// refreshMu guards inflight, the refresh currently running, which
+	// concurrent refresh requests join instead of starting their own.
+	refreshMu sync.Mutex
+	inflight  *refreshCall
+}
+
+// computedRegion is a region's ComputeRegion result before the updater's
+// guards touched it.
+type computedRegion struct {
+	cfg  cutoff.Queues
+	data cutoff.RegionData
+}
+
+// cycleReport summarizes the outcome of a cycle per region.
+type cycleReport struct {
+	StartedAt time.Time               `json:"startedAt"`
+	Duration  string                  `json:"duration"`
+	Regions   map[string]regionStatus `json:"regions"`
+
+	unauthorized int
+}
+
+// allFailed reports whether the cycle had regions and every one of them
+// failed.
+func (r cycleReport) allFailed() bool {
+	for _, status := range r.Regions {
+		if status.OK {
+			return false
+		}
+	}
+	return len(r.Regions) > 0
+}
+
+// skippedRegions returns the regions that weren't due for a fetch in the
+// cycle, sorted.
+func (r cycleReport) skippedRegions() []string {
+	var skipped []string
+	for region, status := range r.Regions {
+		if status.Skipped {
+			skipped = append(skipped, region)
+		}
+	}
+	sort.Strings(skipped)
+	return skipped
+}
+
+// failedRegions returns the regions that failed in the cycle, sorted.
+func (r cycleReport) failedRegions() []string {
+	var failed []string
+	for region, status := range r.Regions {
+		if !status.OK {
+			failed = append(failed, region)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+type regionStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	// Skipped marks a region that wasn't due for a fetch, under its own
+	// poll_interval or the global one, and republished its previous cutoffs.
+	Skipped bool                   `json:"skipped,omitempty"`
+	Queues  map[string]queueStatus `json:"queues,omitempty"`
+}
+
+type refreshCall struct {
+	done   chan struct{}
+	report cycleReport
+	err    error
+}
+
+// runCycle fetches and computes the cutoffs of every configured region and
+// publishes them. It returns an error wrapping riot.ErrUnauthorized when Riot
+// rejected the API key for every region it fetched, and abandons the cycle
+// without publishing anything when ctx is canceled before the regions are
+// collected.
+func (u *updater) runCycle(ctx context.Context) (cycleReport, error) {
+	return u.cycle(ctx, false)
+}
+
+// cycle runs a cycle, see runCycle. When force is set every region is
+// fetched, even those not due under their own poll_interval.
+func (u *updater) cycle(ctx context.Context, force bool) (cycleReport, error) {
+	select {
+	case u.cycleSem <- struct{}{}:
+		defer func() { <-u.cycleSem }()
+	case <-ctx.Done():
+		return cycleReport{}, fmt.Errorf("cycle abandoned: %w", ctx.Err())
+	}
+
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "cycle")
+	defer span.End()
+	cfg := u.watcher.current()
+	u.configInterval.Store(int64(cfg.PollInterval))
+	span.SetAttributes(attribute.Int("regions", len(cfg.Regions)))
+	for region := range u.lastGood {
+		if _, ok := cfg.Regions[region]; !ok {
+			delete(u.lastGood, region)
+			delete(u.computed, region)
+			delete(u.fetchedAt, region)
+		}
+	}
+
+	cycleCtx, cancel := context.WithTimeout(ctx, u.settings.CycleTimeout)
+	outputData, report := u.collect(cycleCtx, cfg, force)
+	cancel()
+	report.StartedAt = start.UTC()
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+	span.SetAttributes(attribute.Int64("latency_ms", time.Since(start).Milliseconds()))
+	if err := ctx.Err(); err != nil {
+		// Shutting down: the regions cut short would publish as failed, so
+		// leave the previous output in place.
+		span.SetStatus(codes.Error, "cycle abandoned")
+		return report, fmt.Errorf("cycle abandoned: %w", err)
+	}
+	if fetched := len(cfg.Regions) - len(report.skippedRegions()); fetched > 0 && report.unauthorized == fetched {
+		span.SetStatus(codes.Error, "every region was unauthorized")
+		return report, fmt.Errorf("all %d regions failed: %w", report.unauthorized, riot.ErrUnauthorized)
+	}
+
+	u.store.set(outputData)
+	u.publish(ctx, outputData, report)
+	if u.settings.WriteStatus && !u.settings.DryRun {
+		if err := writeStatusFile(u.settings.OutputDir, report); err != nil {
+			slog.Error("Writing status report failed", "error", err)
+		}
+	}
+	return report, nil
+}
+
+// refresh runs an out-of-band cycle fetching every region, regardless of
+// their poll_interval, and returns its report. Calls made while a
+// refresh is already running wait for that refresh instead of starting
+// another one. The cycle runs on ctx regardless of whether the waiting caller
+// gives up, which it can do through waitCtx.
+func (u *updater) refresh(ctx, waitCtx context.Context) (cycleReport, error) {
+	u.refreshMu.Lock()
+	call := u.inflight
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		u.inflight = call
+		go func() {
+			call.report, call.err = u.cycle(ctx, true)
+			u.refreshMu.Lock()
+			u.inflight = nil
+			u.refreshMu.Unlock()
+			close(call.done)
+		}()
+	}
+	u.refreshMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.report, call.err
+	case <-waitCtx.Done():
+		return cycleReport{}, waitCtx.Err()
+	}
+}
+
+// warmStart loads the cutoffs published before the last restart from
+// current/cutoffs.json, so they are served right away and serve as the
+// baseline of the first cycle. A missing or unreadable file means a cold
+// start.
+func (u *updater) warmStart() {
+	filePath := filepath.Join(u.settings.OutputDir, "current", "cutoffs.json")
+	info, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var previous output.File
+	if err == nil {
+		var raw []byte
+		if raw, err = os.ReadFile(filePath); err == nil {
+			err = json.Unmarshal(raw, &previous)
+		}
+	}
+	if err != nil {
+		slog.Warn("Loading previous cutoffs failed, starting cold", "path", filePath, "error", err)
+		return
+	}
+
+	cfg := u.watcher.current()
+	for region, data := range previous.Regions {
+		if _, ok := cfg.Regions[region]; !ok {
+			delete(previous.Regions, region)
+			continue
+		}
+		u.lastGood[region] = data
+		u.fetchedAt[region] = data.UpdatedAt
+	}
+	u.store.setAt(previous.Regions, info.ModTime())
+	slog.Info("Loaded previous cutoffs", "path", filePath, "regions", len(previous.Regions), "written_at", info.ModTime().UTC())
+}
+
+// latencies returns the latencies of the latest cycle.
+func (u *updater) latencies() latencyReport {
+	u.latencyMu.Lock()
+	defer u.latencyMu.Unlock()
+	return u.latency
+}
+
+// defaultPollInterval is the poll interval when neither the settings nor the
+// config set one.
+const defaultPollInterval = time.Minute
+
+// pollInterval returns the configured time between cycles: -interval or
+// POLL_INTERVAL, else the config file's poll_interval, else
+// defaultPollInterval.
+func (u *updater) pollInterval() time.Duration {
+	return cmp.Or(u.settings.PollInterval, time.Duration(u.configInterval.Load()), defaultPollInterval)
+}
+
+// nextInterval returns how long to wait before the next cycle: the poll
+// interval, adapted to the rate-limit budget left when ADAPTIVE_POLL is set
+// and the fetcher reports it, or less when a region with its own shorter
+// poll_interval is due before. Such an early cycle only fetches the regions
+// that are due.
+func (u *updater) nextInterval() time.Duration {
+	s := u.settings
+	next := u.pollInterval()
+	if reporter, ok := u.fetcher.(rateLimitReporter); ok && s.AdaptivePoll {
+		usage, observed := reporter.TakeRateUsage()
+		next = nextPollInterval(next, s.MinPollInterval, s.MaxPollInterval, usage, observed)
+		slog.Debug("Scheduled next cycle", "rate_limit_usage", usage, "interval", next)
+	}
+
+	u.scheduleMu.Lock()
+	defer u.scheduleMu.Unlock()
+	now := time.Now()
+	if u.globalFetched || u.globalDue.IsZero() {
+		u.globalDue, u.globalFetched = now.Add(next), false
+	}
+	wait := u.globalDue.Sub(now)
+	if !u.regionsDue.IsZero() && u.regionsDue.Before(u.globalDue) {
+		wait = max(u.regionsDue.Sub(now), 0)
+		slog.Debug("Scheduled next cycle for regions with their own poll_interval", "interval", wait)
+	}
+	return wait
+}
+
+// collect processes every region of cfg and merges the results with the last
+// good data, reporting the outcome of every region. Regions not due under
+// their own poll_interval, or the global one when they have none, republish
+// their last good data, unless force is set or their config changed since
+// they were last computed.
+func (u *updater) collect(ctx context.Context, cfg config, force bool) (map[string]cutoff.RegionData, cycleReport) {
+	s := u.settings
+	if f, ok := u.fetcher.(*riot.Fetcher); ok {
+		f.SetRegionBaseURLs(cfg.baseURLs())
+	}
+	outputData := make(map[string]cutoff.RegionData)
+	resultChan := make(chan RegionResult, len(cfg.Regions))
+	latency := newLatencyRecorder(time.Now())
+	var fetcher cutoff.Fetcher = timedFetcher{tracedFetcher{u.fetcher}, latency}
+	if s.RetryBudget > 0 {
+		fetcher = timedFetcher{retryingFetcher{
+			Fetcher:    tracedFetcher{u.fetcher},
+			budget:     &retryBudget{left: s.RetryBudget},
+			maxRetries: s.MaxRetries,
+			backoff:    s.RetryBackoff,
+		}, latency}
+	}
+	sem := make(chan struct{}, s.MaxConcurrency)
+	var wg sync.WaitGroup
+
+	cycleStart := time.Now()
+	u.scheduleMu.Lock()
+	global := force || !cycleStart.Before(u.globalDue)
+	u.scheduleMu.Unlock()
+	skipped := make(map[string]cutoff.RegionData)
+	for region, regionCfg := range cfg.Regions {
+		computed, wasComputed := u.computed[region]
+		configChanged := wasComputed && !reflect.DeepEqual(computed.cfg, regionCfg)
+		due := global
+		if regionCfg.PollInterval > 0 {
+			due = cycleStart.Sub(u.fetchedAt[region]) >= regionCfg.PollInterval
+		}
+		if previous, ok := u.lastGood[region]; ok && !force && !configChanged && !due {
+			skipped[region] = previous
+			continue
+		}
+		opts := u.opts
+		if wasComputed && !configChanged {
+			opts.Previous = &computed.data
+		}
+		wg.Add(1)
+		go func(region string, regionCfg cutoff.Queues) {
+			defer wg.Done()
+			// Spread the region starts over the jitter window so the
+			// cycle doesn't open with a burst against Riot.
+			if s.RegionStartJitter > 0 {
+				timer := time.NewTimer(rand.N(s.RegionStartJitter))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+				}
+			}
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				resultChan <- RegionResult{Region: region, Err: fmt.Errorf("region not started: %w", ctx.Err())}
+				return
+			}
+			regionCtx, cancel := context.WithTimeout(ctx, s.RegionTimeout)
+			defer cancel()
+			regionCtx, span := tracer.Start(regionCtx, "processRegion", trace.WithAttributes(attribute.String("region", region)))
+			start := time.Now()
+			data, err := cutoff.ComputeRegion(regionCtx, fetcher, region, regionCfg, opts)
+			latency.region(region, time.Since(start))
+			endSpan(span, start, err)
+			resultChan <- RegionResult{Region: region, Data: data, Err: err}
+		}(region, regionCfg)
+	}
+
+	wg.Wait()
+	close(resultChan)
+
+	latencies := latency.finish()
+	latencies.log()
+	u.latencyMu.Lock()
+	u.latency = latencies
+	u.latencyMu.Unlock()
+
+	cycleExpired := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	var cancelled []string
+	u.churn = nil
+	report := cycleReport{Regions: make(map[string]regionStatus, len(cfg.Regions))}
+	for region, previous := range skipped {
+		report.Regions[region] = regionStatus{OK: true, Skipped: true, Queues: regionQueueStatus(previous, nil, s.APIKey)}
+		outputData[region] = previous
+	}
+	if len(skipped) > 0 {
+		slog.Debug("Regions not due for a fetch", "regions", slices.Sorted(maps.Keys(skipped)))
+	}
+	u.schedule(cfg, cycleStart, skipped, global)
+	for result := range resultChan {
+		if result.Err != nil {
+			report.Regions[result.Region] = regionStatus{
+				Error:  sanitizeError(result.Err, s.APIKey),
+				Queues: regionQueueStatus(result.Data, result.Err, s.APIKey),
+			}
+			if cycleExpired && errors.Is(result.Err, context.DeadlineExceeded) {
+				cancelled = append(cancelled, result.Region)
+			}
+			slog.Error("Processing region failed", "region", result.Region, "error", result.Err)
+			if errors.Is(result.Err, riot.ErrUnauthorized) {
+				report.unauthorized++
+			}
+			if previous, ok := u.lastGood[result.Region]; ok {
+				previous.Stale = true
+				outputData[result.Region] = previous
+			}
+			continue
+		}
+		if u.computed == nil {
+			u.computed = make(map[string]computedRegion)
+		}
+		u.computed[result.Region] = computedRegion{cfg: cfg.Regions[result.Region], data: result.Data}
+		if previous, ok := u.lastGood[result.Region]; ok {
+			result.Data = s.JumpGuard.suppressJumps(result.Region, previous, result.Data)
+			result.Data = holdSmallChanges(previous, result.Data, s.MinCutoffChange)
+			if s.KeepLastOnProvisional {
+				result.Data = keepGenuineCutoffs(previous, result.Data)
+			}
+		}
+		result.Data.UpdatedAt = time.Now().UTC()
+		if u.opts.TrackPlayers {
+			var previous *cutoff.RegionData
+			if data, ok := u.lastGood[result.Region]; ok {
+				previous = &data
+			}
+			u.churn = append(u.churn, regionChurn(result.Region, previous, result.Data)...)
+		}
+		report.Regions[result.Region] = regionStatus{OK: true, Queues: regionQueueStatus(result.Data, nil, s.APIKey)}
+		u.lastGood[result.Region] = result.Data
+		u.fetchedAt[result.Region] = cycleStart
+		outputData[result.Region] = result.Data
+		logRegionCutoffs(result.Region, result.Data)
+	}
+
+	sort.SliceStable(u.churn, func(i, j int) bool { return u.churn[i].Region < u.churn[j].Region })
+	for _, c := range u.churn {
+		slog.Debug("Tier churn", "region", c.Region, "queue", c.Queue, "tier", c.Tier,
+			"promotions", c.Promotions, "demotions", c.Demotions)
+	}
+
+	if len(cancelled) > 0 {
+		sort.Strings(cancelled)
+		slog.Warn("Cycle deadline cancelled regions", "regions", cancelled, "timeout", s.CycleTimeout)
+	}
+	return outputData, report
+}
+
+// schedule records when the regions of cfg are next due after the cycle
+// started at cycleStart, which fetched the regions without their own
+// poll_interval when global is set. A region with its own poll_interval is
+// due that long after it was last fetched or, when it was attempted in the
+// cycle, after cycleStart, so a failing region isn't retried right away.
+func (u *updater) schedule(cfg config, cycleStart time.Time, skipped map[string]cutoff.RegionData, global bool) {
+	var regionsDue time.Time
+	for region, regionCfg := range cfg.Regions {
+		if regionCfg.PollInterval == 0 {
+			continue
+		}
+		due := cycleStart.Add(regionCfg.PollInterval)
+		if _, ok := skipped[region]; ok {
+			due = u.fetchedAt[region].Add(regionCfg.PollInterval)
+		}
+		if regionsDue.IsZero() || due.Before(regionsDue) {
+			regionsDue = due
+		}
+	}
+
+	u.scheduleMu.Lock()
+	defer u.scheduleMu.Unlock()
+	u.regionsDue = regionsDue
+	u.globalFetched = u.globalFetched || global
+}
+
+// publish reports the cycle's changes and hands outputData to every
+// configured sink. The regions report skipped aren't recorded in the
+// database again. In dry-run mode only the logging happens.
+func (u *updater) publish(ctx context.Context, outputData map[string]cutoff.RegionData, report cycleReport) {
+	s := u.settings
+
+	var changes []cutoffChange
+	previousOutput := u.store.previous()
+	if previousOutput != nil {
+		changes = diffCutoffs(previousOutput, outputData)
+		for _, change := range changes {
+			slog.Info("Cutoff changed", "region", change.Region, "queue", change.Queue, "tier", change.Tier,
+				"previous", change.Previous, "current", change.Current, "delta", change.Delta)
+		}
+	}
+	hasBaseline := previousOutput != nil
+
+	if s.DryRun {
+		return
+	}
+
+	if u.db != nil {
+		dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		recorded := maps.Clone(outputData)
+		for _, region := range report.skippedRegions() {
+			delete(recorded, region)
+		}
+		if err := u.db.record(dbCtx, time.Now(), recorded); err != nil {
+			slog.Error("Recording cutoffs in database failed", "error", err)
+		}
+		cancel()
+	}
+
+	u.trackExtremes(ctx, outputData)
+	if err := writeExtremesFile(s.OutputDir, u.extremes.snapshot()); err != nil {
+		slog.Error("Writing daily extremes failed", "error", err)
+	}
+
+	if s.WriteChurn {
+		if err := writeChurnFile(s.OutputDir, u.churn); err != nil {
+			slog.Error("Writing churn report failed", "error", err)
+		}
+	}
+
+	if hasBaseline {
+		if s.WriteChanges {
+			if err := writeChangesFile(s.OutputDir, changes); err != nil {
+				slog.Error("Writing change report failed", "error", err)
+			}
+		}
+		if u.notifier != nil {
+			u.notifier.notify(changes)
+		}
+	}
+
+	if err := output.Write(s.outputOptions(), outputData); err != nil {
+		slog.Error("Writing cutoffs to files failed", "error", err)
+	} else if u.uploader != nil {
+		uploadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		datedPath := filepath.Join(s.Archive.Dir(time.Now()), "cutoffs.json")
+		if err := u.uploader.uploadFiles(uploadCtx, s.OutputDir, filepath.Join("current", "cutoffs.json"), datedPath); err != nil {
+			slog.Error("Uploading cutoffs to S3 failed", "error", err)
+		}
+		cancel()
+	}
+
+	if today := time.Now().In(s.Archive.Location).Format("2006-01-02"); s.RetentionDays > 0 && today != u.lastPruned {
+		if err := output.PruneArchives(s.OutputDir, s.Archive, s.RetentionDays, time.Now()); err != nil {
+			slog.Error("Pruning archives failed", "error", err)
+		} else {
+			u.lastPruned = today
+		}
+	}
+}
+
+// trackExtremes updates the watermarks with outputData, starting over when the
+// archive period rolls over. With a database the watermarks of a new period
+// are seeded from the rows already recorded in it, so they survive restarts.
+func (u *updater) trackExtremes(ctx context.Context, outputData map[string]cutoff.RegionData) {
+	now, archive := time.Now(), u.settings.Archive
+	if period := now.In(archive.Location).Format(archive.Layout); period != u.extremes.period {
+		u.extremes.reset(period)
+		if u.db != nil {
+			dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			extremes, err := u.db.extremesSince(dbCtx, archive.PeriodStart(now))
+			cancel()
+			if err != nil {
+				slog.Error("Loading daily extremes from database failed", "error", err)
+			}
+			for _, e := range extremes {
+				u.extremes.update(e)
+			}
+		}
+	}
+	u.extremes.observe(outputData)
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+	"github.com/renja-g/lol-lp-cutoff/pkg/riot"
+)
+
+// fakeFetcher serves every region's leagues from the LP of its top player:
+// three Challengers 10 LP apart, four Grandmasters from 100 LP below the top
+// and five Masters from 300 LP below it. Regions can be made to fail or to
+// answer slowly.
+type fakeFetcher struct {
+	mu     sync.Mutex
+	top    map[string]int
+	errs   map[string]error
+	delays map[string]time.Duration
+	calls  map[string]int
+	// started holds the time of each region's first request.
+	started map[string]time.Time
+}
+
+// fakeTiers holds the size of each fake league and how far below the top
+// player it starts.
+var fakeTiers = map[string]struct{ size, below int }{
+	cutoff.LeagueChallenger:  {3, 0},
+	cutoff.LeagueGrandmaster: {4, 100},
+	cutoff.LeagueMaster:      {5, 300},
+}
+
+func newFakeFetcher(top map[string]int) *fakeFetcher {
+	return &fakeFetcher{
+		top:     top,
+		errs:    make(map[string]error),
+		delays:  make(map[string]time.Duration),
+		calls:   make(map[string]int),
+		started: make(map[string]time.Time),
+	}
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, region, league, queueType string) (cutoff.LeagueResponse, error) {
+	f.mu.Lock()
+	if f.calls[region] == 0 {
+		f.started[region] = time.Now()
+	}
+	f.calls[region]++
+	top, err, delay := f.top[region], f.errs[region], f.delays[region]
+	f.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return cutoff.LeagueResponse{}, ctx.Err()
+		}
+	}
+	if err != nil {
+		return cutoff.LeagueResponse{}, err
+	}
+	tier := fakeTiers[league]
+	var resp cutoff.LeagueResponse
+	for i := range tier.size {
+		resp.Entries = append(resp.Entries, cutoff.LeagueEntry{
+			PUUID:        fmt.Sprintf("%s-%s-%s-%d", region, queueType, league, i),
+			LeaguePoints: top - tier.below - 10*i,
+		})
+	}
+	return resp, nil
+}
+
+func (f *fakeFetcher) set(region string, top int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.top[region], f.errs[region] = top, err
+}
+
+func (f *fakeFetcher) callsTo(region string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[region]
+}
+
+// slotsYAML is a config block for region with two Challenger and three
+// Grandmaster slots per queue, which the fakeFetcher's ladders cover.
+func slotsYAML(region string) string {
+	return region + ":\n" +
+		"    solo_duo:\n        challenger: 2\n        grandmaster: 3\n" +
+		"    flex:\n        challenger: 2\n        grandmaster: 3\n"
+}
+
+// testUpdater returns an updater fetching from fetcher, or from Riot when
+// nil, with the config in configYAML, writing its output to a temp dir and
+// starting the regions without jitter. env holds further settings as
+// name/value pairs.
+func testUpdater(t *testing.T, fetcher cutoff.Fetcher, configYAML string, env ...string) *updater {
+	t.Helper()
+	t.Setenv("OUTPUT_DIR", filepath.Join(t.TempDir(), "out"))
+	t.Setenv("REGION_START_JITTER", "0s")
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+	s, err := loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := newConfigWatcher(writeConfig(t, configYAML), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newUpdater(s, watcher)
+	if fetcher != nil {
+		u.fetcher = fetcher
+	}
+	return u
+}
+
+func TestFailedCycleKeepsUpdatedAt(t *testing.T) {
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+	u := testUpdater(t, fetcher, slotsYAML("euw1")+slotsYAML("kr"))
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := u.store.get()
+
+	time.Sleep(10 * time.Millisecond)
+	fetcher.set("euw1", 1500, fmt.Errorf("riot unavailable"))
+	report, err := u.runCycle(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Regions["euw1"].OK {
+		t.Error("euw1 reported OK after its fetch failed")
+	}
+	second, _ := u.store.get()
+
+	euw1 := second["euw1"]
+	if !euw1.Stale {
+		t.Error("failed euw1 isn't marked stale")
+	}
+	if !euw1.UpdatedAt.Equal(first["euw1"].UpdatedAt) || !euw1.RANKED_SOLO_5x5.UpdatedAt.Equal(first["euw1"].RANKED_SOLO_5x5.UpdatedAt) {
+		t.Errorf("failed euw1 updatedAt = %s, want the previous %s", euw1.UpdatedAt, first["euw1"].UpdatedAt)
+	}
+	if euw1.RANKED_SOLO_5x5.Challenger != first["euw1"].RANKED_SOLO_5x5.Challenger {
+		t.Errorf("failed euw1 Challenger cutoff = %d, want the previous %d", euw1.RANKED_SOLO_5x5.Challenger, first["euw1"].RANKED_SOLO_5x5.Challenger)
+	}
+
+	kr := second["kr"]
+	if kr.Stale || !kr.UpdatedAt.After(first["kr"].UpdatedAt) {
+		t.Errorf("kr stale = %v, updatedAt = %s, want a fresh update after %s", kr.Stale, kr.UpdatedAt, first["kr"].UpdatedAt)
+	}
+}
+
+func TestRegionStartJitter(t *testing.T) {
+	regions := []string{"br1", "eun1", "euw1", "jp1", "kr", "la1", "na1", "oc1"}
+	top := make(map[string]int)
+	var configYAML string
+	for _, region := range regions {
+		top[region] = 1500
+		configYAML += slotsYAML(region)
+	}
+	fetcher := newFakeFetcher(top)
+	const jitter = 300 * time.Millisecond
+	u := testUpdater(t, fetcher, configYAML)
+	u.settings.RegionStartJitter = jitter
+
+	start := time.Now()
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first, last := time.Duration(math.MaxInt64), time.Duration(0)
+	for _, region := range regions {
+		offset := fetcher.started[region].Sub(start)
+		first, last = min(first, offset), max(last, offset)
+	}
+	if last > jitter+100*time.Millisecond {
+		t.Errorf("last region started after %s, want within the %s jitter", last, jitter)
+	}
+	// Eight uniform offsets all landing within 10ms of each other is
+	// practically impossible.
+	if last-first < 10*time.Millisecond {
+		t.Errorf("regions started within %s of each other, want them spread over %s", last-first, jitter)
+	}
+}
+
+func TestMinimalModeFetchesOnlyChallenger(t *testing.T) {
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+	u := testUpdater(t, fetcher, slotsYAML("euw1")+slotsYAML("kr"), "MINIMAL_MODE", "true")
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	outputData, _ := u.store.get()
+	for region, top := range map[string]int{"euw1": 1500, "kr": 1800} {
+		if calls := fetcher.callsTo(region); calls != 1 {
+			t.Errorf("%s: %d fetches, want 1", region, calls)
+		}
+		data := outputData[region]
+		if solo := data.RANKED_SOLO_5x5; !solo.ChallengerOnly || solo.Challenger != top-10 {
+			t.Errorf("%s solo/duo = %+v, want the Challenger cutoff %d only", region, solo, top-10)
+		}
+		if data.RANKED_FLEX_SR.Computed() {
+			t.Errorf("%s flex was computed in minimal mode", region)
+		}
+	}
+}
+
+func TestWarmStart(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantRegions []string
+	}{
+		{"current file", `{"schemaVersion":1,"euw1":{"updatedAt":"2024-03-31T12:00:00Z"},"na1":{"updatedAt":"2024-03-31T12:00:00Z"}}`, []string{"euw1"}},
+		{"file without schemaVersion", `{"euw1":{"updatedAt":"2024-03-31T12:00:00Z"}}`, []string{"euw1"}},
+		{"corrupt file", `{"euw1":`, nil},
+		{"missing file", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := testUpdater(t, newFakeFetcher(nil), slotsYAML("euw1"))
+			if tt.content != "" {
+				currentDir := filepath.Join(u.settings.OutputDir, "current")
+				if err := os.MkdirAll(currentDir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(currentDir, "cutoffs.json"), []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			u.warmStart()
+			data, _ := u.store.get()
+			if got := slices.Sorted(maps.Keys(data)); !slices.Equal(got, tt.wantRegions) {
+				t.Errorf("loaded regions %v, want %v", got, tt.wantRegions)
+			}
+			if got := slices.Sorted(maps.Keys(u.lastGood)); !slices.Equal(got, tt.wantRegions) {
+				t.Errorf("baseline regions %v, want %v", got, tt.wantRegions)
+			}
+			if tt.wantRegions == nil {
+				return
+			}
+			want := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+			if got := u.fetchedAt["euw1"]; !got.Equal(want) {
+				t.Errorf("euw1 fetched at %s, want the file's updatedAt %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCollectUsesRegionBaseURLs(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"entries":[{"puuid":"a","leaguePoints":1500},{"puuid":"b","leaguePoints":1400},{"puuid":"c","leaguePoints":1300},{"puuid":"d","leaguePoints":1200},{"puuid":"e","leaguePoints":1100}]}`)
+	}))
+	defer srv.Close()
+
+	// riot.invalid doesn't resolve, so kr can only be fetched from its
+	// base_url. collect is called directly, as the fetch command does.
+	u := testUpdater(t, nil, "kr:\n    base_url: "+srv.URL+"\n"+strings.TrimPrefix(slotsYAML("kr"), "kr:\n"),
+		"RIOT_API_KEY", "test-key", "RIOT_BASE_URL", "http://{platform}.riot.invalid")
+	outputData, report := u.collect(context.Background(), u.watcher.current(), false)
+	if !report.Regions["kr"].OK {
+		t.Fatalf("kr report = %+v, want it fetched from its base_url", report.Regions["kr"])
+	}
+	if requests.Load() == 0 {
+		t.Error("the base_url server received no requests")
+	}
+	if got := outputData["kr"].RANKED_SOLO_5x5.Challenger; got != 1400 {
+		t.Errorf("kr Challenger cutoff = %d, want 1400", got)
+	}
+}
+
+// reportingFetcher is a fakeFetcher that reports a fixed rate-limit usage.
+type reportingFetcher struct {
+	*fakeFetcher
+	usage float64
+}
+
+func (f reportingFetcher) TakeRateUsage() (float64, bool) { return f.usage, true }
+
+func TestNextIntervalHonorsConfiguredInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		configYAML string
+		env        []string
+		usage      float64
+		want       time.Duration
+	}{
+		{"default", slotsYAML("euw1"), nil, 0.1, defaultPollInterval},
+		{"POLL_INTERVAL", slotsYAML("euw1"), []string{"POLL_INTERVAL", "2m"}, 0.1, 2 * time.Minute},
+		{"config poll_interval", "poll_interval: 3m\n" + slotsYAML("euw1"), nil, 0.95, 3 * time.Minute},
+		{"POLL_INTERVAL over the config's", "poll_interval: 3m\n" + slotsYAML("euw1"), []string{"POLL_INTERVAL", "2m"}, 0.95, 2 * time.Minute},
+		{"adaptive with plenty of budget", slotsYAML("euw1"), []string{"POLL_INTERVAL", "2m", "ADAPTIVE_POLL", "true"}, 0.1, time.Minute},
+		{"adaptive with the budget nearly used", slotsYAML("euw1"), []string{"POLL_INTERVAL", "2m", "ADAPTIVE_POLL", "true"}, 0.8, 4 * time.Minute},
+		{"adaptive capped", slotsYAML("euw1"), []string{"POLL_INTERVAL", "2m", "ADAPTIVE_POLL", "true", "MAX_POLL_INTERVAL", "5m"}, 0.95, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := reportingFetcher{newFakeFetcher(map[string]int{"euw1": 1500}), tt.usage}
+			u := testUpdater(t, fetcher, tt.configYAML, tt.env...)
+			if _, err := u.runCycle(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := u.nextInterval(); got != tt.want {
+				t.Errorf("next interval = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// hourlyYAML is slotsYAML for region with a poll_interval of one hour.
+func hourlyYAML(region string) string {
+	return strings.Replace(slotsYAML(region), region+":\n", region+":\n    poll_interval: 1h\n", 1)
+}
+
+func TestRegionPollInterval(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		cycle func(t *testing.T, u *updater) (cycleReport, error)
+		fetch bool
+	}{
+		{"not due", func(t *testing.T, u *updater) (cycleReport, error) {
+			return u.runCycle(ctx)
+		}, false},
+		{"forced refresh", func(t *testing.T, u *updater) (cycleReport, error) {
+			return u.refresh(ctx, ctx)
+		}, true},
+		{"config changed", func(t *testing.T, u *updater) (cycleReport, error) {
+			replaceConfig(t, u.watcher.path, strings.Replace(hourlyYAML("euw1"), "challenger: 2", "challenger: 1", 1))
+			return u.runCycle(ctx)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := newFakeFetcher(map[string]int{"euw1": 1500})
+			u := testUpdater(t, fetcher, hourlyYAML("euw1"))
+			u.db = testDB(t)
+			if _, err := u.runCycle(ctx); err != nil {
+				t.Fatal(err)
+			}
+			calls, rows := fetcher.callsTo("euw1"), recordedRows(t, u.db, "euw1")
+
+			report, err := tt.cycle(t, u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fetched := fetcher.callsTo("euw1") > calls; fetched != tt.fetch {
+				t.Errorf("euw1 fetched = %v, want %v", fetched, tt.fetch)
+			}
+			if skipped := report.Regions["euw1"].Skipped; skipped == tt.fetch {
+				t.Errorf("euw1 skipped = %v, want %v", skipped, !tt.fetch)
+			}
+			if recorded := recordedRows(t, u.db, "euw1") > rows; recorded != tt.fetch {
+				t.Errorf("euw1 recorded again = %v, want %v", recorded, tt.fetch)
+			}
+		})
+	}
+}
+
+func TestUnauthorizedIgnoresSkippedRegions(t *testing.T) {
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+	u := testUpdater(t, fetcher, hourlyYAML("euw1")+slotsYAML("kr"))
+	if _, err := u.runCycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher.set("kr", 1800, riot.ErrUnauthorized)
+	report, err := u.runCycle(context.Background())
+	if !report.Regions["euw1"].Skipped {
+		t.Fatalf("euw1 status = %+v, want it skipped", report.Regions["euw1"])
+	}
+	if !errors.Is(err, riot.ErrUnauthorized) {
+		t.Errorf("runCycle error = %v, want riot.ErrUnauthorized as every fetched region was rejected", err)
+	}
+}
+
+// recordedRows returns the number of cutoffs recorded in db for region.
+func recordedRows(t *testing.T, db *cutoffDB, region string) int {
+	t.Helper()
+	var rows int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM cutoffs WHERE region = ?`, region).Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestRegionPollIntervalShorterThanGlobal(t *testing.T) {
+	fetcher := newFakeFetcher(map[string]int{"euw1": 1500, "kr": 1800})
+	kr := strings.Replace(slotsYAML("kr"), "kr:\n", "kr:\n    poll_interval: 1m\n", 1)
+	u := testUpdater(t, fetcher, "poll_interval: 5m\n"+slotsYAML("euw1")+kr)
+	ctx := context.Background()
+	if _, err := u.runCycle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if wait := u.nextInterval(); wait > time.Minute || wait < 50*time.Second {
+		t.Fatalf("next interval = %s, want kr's 1m rather than the global 5m", wait)
+	}
+	globalDue := u.globalDue
+
+	// kr comes due: the early cycle fetches it alone.
+	u.fetchedAt["kr"] = u.fetchedAt["kr"].Add(-time.Minute)
+	euw1Calls, krCalls := fetcher.callsTo("euw1"), fetcher.callsTo("kr")
+	report, err := u.runCycle(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetcher.callsTo("kr") == krCalls || report.Regions["kr"].Skipped {
+		t.Error("kr wasn't fetched once due under its own poll_interval")
+	}
+	if fetcher.callsTo("euw1") != euw1Calls || !report.Regions["euw1"].Skipped {
+		t.Error("euw1 was fetched before the global poll interval elapsed")
+	}
+	if wait := u.nextInterval(); wait > time.Minute {
+		t.Errorf("next interval after the early cycle = %s, want at most kr's 1m", wait)
+	}
+	if !u.globalDue.Equal(globalDue) {
+		t.Errorf("global due = %s after the early cycle, want it kept at %s", u.globalDue, globalDue)
+	}
+
+	// The global interval elapses: euw1 is fetched again.
+	u.globalDue = time.Now()
+	euw1Calls = fetcher.callsTo("euw1")
+	if _, err := u.runCycle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if fetcher.callsTo("euw1") == euw1Calls {
+		t.Error("euw1 wasn't fetched once the global poll interval elapsed")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+// Build information, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=...".
+var (
+	version   = "dev"
+	commit    = "dev"
+	buildDate = "dev"
+)
+
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+}
+
+func currentBuildInfo() buildInfo {
+	return buildInfo{Version: version, Commit: commit, BuildDate: buildDate}
+}
+
+// defaultUserAgent identifies this application to the Riot API.
+func defaultUserAgent() string {
+	return "league-lp-cutoff/" + version
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// jsonSink is a Notifier that POSTs each change as JSON, shaped by payload,
+// to a URL. It backs the webhook, Slack and Discord notifiers, which only
+// differ in the payload they expect.
+type jsonSink struct {
+	url     string
+	client  *http.Client
+	payload func(change cutoffChange) any
+}
+
+func newJSONSink(target string, payload func(change cutoffChange) any) (*jsonSink, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", target)
+	}
+	return &jsonSink{url: target, client: &http.Client{Timeout: 10 * time.Second}, payload: payload}, nil
+}
+
+// newWebhookSink posts the change itself.
+func newWebhookSink(target string) (Notifier, error) {
+	return newJSONSink(target, func(change cutoffChange) any { return change })
+}
+
+// newSlackSink posts to a Slack incoming webhook.
+func newSlackSink(target string) (Notifier, error) {
+	return newJSONSink(target, func(change cutoffChange) any {
+		return map[string]string{"text": changeMessage(change)}
+	})
+}
+
+// newDiscordSink posts to a Discord webhook.
+func newDiscordSink(target string) (Notifier, error) {
+	return newJSONSink(target, func(change cutoffChange) any {
+		return map[string]string{"content": changeMessage(change)}
+	})
+}
+
+func (s *jsonSink) Notify(ctx context.Context, change cutoffChange) error {
+	body, err := json.Marshal(s.payload(change))
+	if err != nil {
+		return fmt.Errorf("marshal notification payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint responded with status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// changeMessage renders change for chat notifiers, e.g. "euw1
+// RANKED_SOLO_5x5 challenger cutoff up 25 LP: 700 -> 725".
+func changeMessage(change cutoffChange) string {
+	return fmt.Sprintf("%s %s %s cutoff %s %d LP: %d -> %d",
+		change.Region, change.Queue, change.Tier, change.Direction, abs(change.Delta), change.Previous, change.Current)
+}
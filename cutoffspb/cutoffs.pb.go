@@ -0,0 +1,397 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: cutoffs.proto
+
+package cutoffspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetCutoffsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCutoffsRequest) Reset() {
+	*x = GetCutoffsRequest{}
+	mi := &file_cutoffs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCutoffsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCutoffsRequest) ProtoMessage() {}
+
+func (x *GetCutoffsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cutoffs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCutoffsRequest.ProtoReflect.Descriptor instead.
+func (*GetCutoffsRequest) Descriptor() ([]byte, []int) {
+	return file_cutoffs_proto_rawDescGZIP(), []int{0}
+}
+
+type GetCutoffsResponse struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Regions       map[string]*RegionCutoffs `protobuf:"bytes,1,rep,name=regions,proto3" json:"regions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	UpdatedAt     *timestamppb.Timestamp    `protobuf:"bytes,2,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCutoffsResponse) Reset() {
+	*x = GetCutoffsResponse{}
+	mi := &file_cutoffs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCutoffsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCutoffsResponse) ProtoMessage() {}
+
+func (x *GetCutoffsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cutoffs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCutoffsResponse.ProtoReflect.Descriptor instead.
+func (*GetCutoffsResponse) Descriptor() ([]byte, []int) {
+	return file_cutoffs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetCutoffsResponse) GetRegions() map[string]*RegionCutoffs {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *GetCutoffsResponse) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type GetRegionCutoffsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Region        string                 `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRegionCutoffsRequest) Reset() {
+	*x = GetRegionCutoffsRequest{}
+	mi := &file_cutoffs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRegionCutoffsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRegionCutoffsRequest) ProtoMessage() {}
+
+func (x *GetRegionCutoffsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cutoffs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRegionCutoffsRequest.ProtoReflect.Descriptor instead.
+func (*GetRegionCutoffsRequest) Descriptor() ([]byte, []int) {
+	return file_cutoffs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetRegionCutoffsRequest) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+type RegionCutoffs struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Region         string                 `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`
+	RankedSolo_5X5 *QueueCutoffs          `protobuf:"bytes,2,opt,name=ranked_solo_5x5,json=rankedSolo5x5,proto3" json:"ranked_solo_5x5,omitempty"`
+	RankedFlexSr   *QueueCutoffs          `protobuf:"bytes,3,opt,name=ranked_flex_sr,json=rankedFlexSr,proto3" json:"ranked_flex_sr,omitempty"`
+	Degraded       []string               `protobuf:"bytes,4,rep,name=degraded,proto3" json:"degraded,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Stale          bool                   `protobuf:"varint,6,opt,name=stale,proto3" json:"stale,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RegionCutoffs) Reset() {
+	*x = RegionCutoffs{}
+	mi := &file_cutoffs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegionCutoffs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegionCutoffs) ProtoMessage() {}
+
+func (x *RegionCutoffs) ProtoReflect() protoreflect.Message {
+	mi := &file_cutoffs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegionCutoffs.ProtoReflect.Descriptor instead.
+func (*RegionCutoffs) Descriptor() ([]byte, []int) {
+	return file_cutoffs_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RegionCutoffs) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *RegionCutoffs) GetRankedSolo_5X5() *QueueCutoffs {
+	if x != nil {
+		return x.RankedSolo_5X5
+	}
+	return nil
+}
+
+func (x *RegionCutoffs) GetRankedFlexSr() *QueueCutoffs {
+	if x != nil {
+		return x.RankedFlexSr
+	}
+	return nil
+}
+
+func (x *RegionCutoffs) GetDegraded() []string {
+	if x != nil {
+		return x.Degraded
+	}
+	return nil
+}
+
+func (x *RegionCutoffs) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *RegionCutoffs) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+type QueueCutoffs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Challenger    int32                  `protobuf:"varint,1,opt,name=challenger,proto3" json:"challenger,omitempty"`
+	Grandmaster   int32                  `protobuf:"varint,2,opt,name=grandmaster,proto3" json:"grandmaster,omitempty"`
+	Provisional   bool                   `protobuf:"varint,3,opt,name=provisional,proto3" json:"provisional,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueCutoffs) Reset() {
+	*x = QueueCutoffs{}
+	mi := &file_cutoffs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueueCutoffs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueueCutoffs) ProtoMessage() {}
+
+func (x *QueueCutoffs) ProtoReflect() protoreflect.Message {
+	mi := &file_cutoffs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueueCutoffs.ProtoReflect.Descriptor instead.
+func (*QueueCutoffs) Descriptor() ([]byte, []int) {
+	return file_cutoffs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *QueueCutoffs) GetChallenger() int32 {
+	if x != nil {
+		return x.Challenger
+	}
+	return 0
+}
+
+func (x *QueueCutoffs) GetGrandmaster() int32 {
+	if x != nil {
+		return x.Grandmaster
+	}
+	return 0
+}
+
+func (x *QueueCutoffs) GetProvisional() bool {
+	if x != nil {
+		return x.Provisional
+	}
+	return false
+}
+
+var File_cutoffs_proto protoreflect.FileDescriptor
+
+const file_cutoffs_proto_rawDesc = "" +
+	"\n" +
+	"\rcutoffs.proto\x12\vlpcutoff.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x13\n" +
+	"\x11GetCutoffsRequest\"\xef\x01\n" +
+	"\x12GetCutoffsResponse\x12F\n" +
+	"\aregions\x18\x01 \x03(\v2,.lpcutoff.v1.GetCutoffsResponse.RegionsEntryR\aregions\x129\n" +
+	"\n" +
+	"updated_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x1aV\n" +
+	"\fRegionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x120\n" +
+	"\x05value\x18\x02 \x01(\v2\x1a.lpcutoff.v1.RegionCutoffsR\x05value:\x028\x01\"1\n" +
+	"\x17GetRegionCutoffsRequest\x12\x16\n" +
+	"\x06region\x18\x01 \x01(\tR\x06region\"\x98\x02\n" +
+	"\rRegionCutoffs\x12\x16\n" +
+	"\x06region\x18\x01 \x01(\tR\x06region\x12A\n" +
+	"\x0franked_solo_5x5\x18\x02 \x01(\v2\x19.lpcutoff.v1.QueueCutoffsR\rrankedSolo5x5\x12?\n" +
+	"\x0eranked_flex_sr\x18\x03 \x01(\v2\x19.lpcutoff.v1.QueueCutoffsR\frankedFlexSr\x12\x1a\n" +
+	"\bdegraded\x18\x04 \x03(\tR\bdegraded\x129\n" +
+	"\n" +
+	"updated_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x14\n" +
+	"\x05stale\x18\x06 \x01(\bR\x05stale\"r\n" +
+	"\fQueueCutoffs\x12\x1e\n" +
+	"\n" +
+	"challenger\x18\x01 \x01(\x05R\n" +
+	"challenger\x12 \n" +
+	"\vgrandmaster\x18\x02 \x01(\x05R\vgrandmaster\x12 \n" +
+	"\vprovisional\x18\x03 \x01(\bR\vprovisional2\xb4\x01\n" +
+	"\rCutoffService\x12M\n" +
+	"\n" +
+	"GetCutoffs\x12\x1e.lpcutoff.v1.GetCutoffsRequest\x1a\x1f.lpcutoff.v1.GetCutoffsResponse\x12T\n" +
+	"\x10GetRegionCutoffs\x12$.lpcutoff.v1.GetRegionCutoffsRequest\x1a\x1a.lpcutoff.v1.RegionCutoffsB,Z*github.com/renja-g/lol-lp-cutoff/cutoffspbb\x06proto3"
+
+var (
+	file_cutoffs_proto_rawDescOnce sync.Once
+	file_cutoffs_proto_rawDescData []byte
+)
+
+func file_cutoffs_proto_rawDescGZIP() []byte {
+	file_cutoffs_proto_rawDescOnce.Do(func() {
+		file_cutoffs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_cutoffs_proto_rawDesc), len(file_cutoffs_proto_rawDesc)))
+	})
+	return file_cutoffs_proto_rawDescData
+}
+
+var file_cutoffs_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_cutoffs_proto_goTypes = []any{
+	(*GetCutoffsRequest)(nil),       // 0: lpcutoff.v1.GetCutoffsRequest
+	(*GetCutoffsResponse)(nil),      // 1: lpcutoff.v1.GetCutoffsResponse
+	(*GetRegionCutoffsRequest)(nil), // 2: lpcutoff.v1.GetRegionCutoffsRequest
+	(*RegionCutoffs)(nil),           // 3: lpcutoff.v1.RegionCutoffs
+	(*QueueCutoffs)(nil),            // 4: lpcutoff.v1.QueueCutoffs
+	nil,                             // 5: lpcutoff.v1.GetCutoffsResponse.RegionsEntry
+	(*timestamppb.Timestamp)(nil),   // 6: google.protobuf.Timestamp
+}
+var file_cutoffs_proto_depIdxs = []int32{
+	5, // 0: lpcutoff.v1.GetCutoffsResponse.regions:type_name -> lpcutoff.v1.GetCutoffsResponse.RegionsEntry
+	6, // 1: lpcutoff.v1.GetCutoffsResponse.updated_at:type_name -> google.protobuf.Timestamp
+	4, // 2: lpcutoff.v1.RegionCutoffs.ranked_solo_5x5:type_name -> lpcutoff.v1.QueueCutoffs
+	4, // 3: lpcutoff.v1.RegionCutoffs.ranked_flex_sr:type_name -> lpcutoff.v1.QueueCutoffs
+	6, // 4: lpcutoff.v1.RegionCutoffs.updated_at:type_name -> google.protobuf.Timestamp
+	3, // 5: lpcutoff.v1.GetCutoffsResponse.RegionsEntry.value:type_name -> lpcutoff.v1.RegionCutoffs
+	0, // 6: lpcutoff.v1.CutoffService.GetCutoffs:input_type -> lpcutoff.v1.GetCutoffsRequest
+	2, // 7: lpcutoff.v1.CutoffService.GetRegionCutoffs:input_type -> lpcutoff.v1.GetRegionCutoffsRequest
+	1, // 8: lpcutoff.v1.CutoffService.GetCutoffs:output_type -> lpcutoff.v1.GetCutoffsResponse
+	3, // 9: lpcutoff.v1.CutoffService.GetRegionCutoffs:output_type -> lpcutoff.v1.RegionCutoffs
+	8, // [8:10] is the sub-list for method output_type
+	6, // [6:8] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_cutoffs_proto_init() }
+func file_cutoffs_proto_init() {
+	if File_cutoffs_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cutoffs_proto_rawDesc), len(file_cutoffs_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_cutoffs_proto_goTypes,
+		DependencyIndexes: file_cutoffs_proto_depIdxs,
+		MessageInfos:      file_cutoffs_proto_msgTypes,
+	}.Build()
+	File_cutoffs_proto = out.File
+	file_cutoffs_proto_goTypes = nil
+	file_cutoffs_proto_depIdxs = nil
+}
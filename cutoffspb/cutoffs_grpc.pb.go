@@ -0,0 +1,167 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: cutoffs.proto
+
+package cutoffspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CutoffService_GetCutoffs_FullMethodName       = "/lpcutoff.v1.CutoffService/GetCutoffs"
+	CutoffService_GetRegionCutoffs_FullMethodName = "/lpcutoff.v1.CutoffService/GetRegionCutoffs"
+)
+
+// CutoffServiceClient is the client API for CutoffService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CutoffService serves the latest computed apex tier cutoffs.
+type CutoffServiceClient interface {
+	// GetCutoffs returns the cutoffs of every region.
+	GetCutoffs(ctx context.Context, in *GetCutoffsRequest, opts ...grpc.CallOption) (*GetCutoffsResponse, error)
+	// GetRegionCutoffs returns the cutoffs of a single region.
+	GetRegionCutoffs(ctx context.Context, in *GetRegionCutoffsRequest, opts ...grpc.CallOption) (*RegionCutoffs, error)
+}
+
+type cutoffServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCutoffServiceClient(cc grpc.ClientConnInterface) CutoffServiceClient {
+	return &cutoffServiceClient{cc}
+}
+
+func (c *cutoffServiceClient) GetCutoffs(ctx context.Context, in *GetCutoffsRequest, opts ...grpc.CallOption) (*GetCutoffsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCutoffsResponse)
+	err := c.cc.Invoke(ctx, CutoffService_GetCutoffs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cutoffServiceClient) GetRegionCutoffs(ctx context.Context, in *GetRegionCutoffsRequest, opts ...grpc.CallOption) (*RegionCutoffs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegionCutoffs)
+	err := c.cc.Invoke(ctx, CutoffService_GetRegionCutoffs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CutoffServiceServer is the server API for CutoffService service.
+// All implementations must embed UnimplementedCutoffServiceServer
+// for forward compatibility.
+//
+// CutoffService serves the latest computed apex tier cutoffs.
+type CutoffServiceServer interface {
+	// GetCutoffs returns the cutoffs of every region.
+	GetCutoffs(context.Context, *GetCutoffsRequest) (*GetCutoffsResponse, error)
+	// GetRegionCutoffs returns the cutoffs of a single region.
+	GetRegionCutoffs(context.Context, *GetRegionCutoffsRequest) (*RegionCutoffs, error)
+	mustEmbedUnimplementedCutoffServiceServer()
+}
+
+// UnimplementedCutoffServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCutoffServiceServer struct{}
+
+func (UnimplementedCutoffServiceServer) GetCutoffs(context.Context, *GetCutoffsRequest) (*GetCutoffsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCutoffs not implemented")
+}
+func (UnimplementedCutoffServiceServer) GetRegionCutoffs(context.Context, *GetRegionCutoffsRequest) (*RegionCutoffs, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRegionCutoffs not implemented")
+}
+func (UnimplementedCutoffServiceServer) mustEmbedUnimplementedCutoffServiceServer() {}
+func (UnimplementedCutoffServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeCutoffServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CutoffServiceServer will
+// result in compilation errors.
+type UnsafeCutoffServiceServer interface {
+	mustEmbedUnimplementedCutoffServiceServer()
+}
+
+func RegisterCutoffServiceServer(s grpc.ServiceRegistrar, srv CutoffServiceServer) {
+	// If the following call panics, it indicates UnimplementedCutoffServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CutoffService_ServiceDesc, srv)
+}
+
+func _CutoffService_GetCutoffs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCutoffsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CutoffServiceServer).GetCutoffs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CutoffService_GetCutoffs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CutoffServiceServer).GetCutoffs(ctx, req.(*GetCutoffsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CutoffService_GetRegionCutoffs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRegionCutoffsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CutoffServiceServer).GetRegionCutoffs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CutoffService_GetRegionCutoffs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CutoffServiceServer).GetRegionCutoffs(ctx, req.(*GetRegionCutoffsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CutoffService_ServiceDesc is the grpc.ServiceDesc for CutoffService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CutoffService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lpcutoff.v1.CutoffService",
+	HandlerType: (*CutoffServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCutoffs",
+			Handler:    _CutoffService_GetCutoffs_Handler,
+		},
+		{
+			MethodName: "GetRegionCutoffs",
+			Handler:    _CutoffService_GetRegionCutoffs_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cutoffs.proto",
+}
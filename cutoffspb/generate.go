@@ -0,0 +1,4 @@
+// Package cutoffspb contains the gRPC API definitions for the cutoff service.
+package cutoffspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cutoffs.proto
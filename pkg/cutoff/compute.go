@@ -0,0 +1,504 @@
+package cutoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fetcher fetches one apex league of a ranked queue on a platform. league is
+// one of LeagueChallenger, LeagueGrandmaster and LeagueMaster, and queueType
+// QueueSoloDuo or QueueFlex.
+type Fetcher interface {
+	Fetch(ctx context.Context, region, league, queueType string) (LeagueResponse, error)
+}
+
+// QueueError is the error of a single queue of a region, as wrapped into the
+// error returned by ComputeRegion.
+type QueueError struct {
+	Queue string
+	Err   error
+}
+
+func (e *QueueError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QueueError) Unwrap() error {
+	return e.Err
+}
+
+// QueueErrors returns every QueueError wrapped in err.
+func QueueErrors(err error) []*QueueError {
+	var errs []*QueueError
+	switch err := err.(type) {
+	case *QueueError:
+		return []*QueueError{err}
+	case interface{ Unwrap() []error }:
+		for _, e := range err.Unwrap() {
+			errs = append(errs, QueueErrors(e)...)
+		}
+	case interface{ Unwrap() error }:
+		errs = QueueErrors(err.Unwrap())
+	}
+	return errs
+}
+
+type leagueDataResult struct {
+	LeagueType string
+	QueueType  string
+	Response   LeagueResponse
+	Err        error
+}
+
+// ComputeRegion fetches the apex leagues of every enabled queue of region and
+// computes their cutoffs. Disabled queues are neither fetched nor computed.
+func ComputeRegion(ctx context.Context, fetcher Fetcher, region string, regionCfg Queues, opts Options) (RegionData, error) {
+	if opts.ChallengerOnly {
+		regionCfg.SoloDuo.Tiers = []string{TierChallenger}
+		regionCfg.Flex.Enabled = new(bool)
+	}
+
+	type leagueFetch struct {
+		LeagueType string
+		QueueType  string
+	}
+	var leagueTypes []leagueFetch
+	for _, queue := range []struct {
+		queueType string
+		cfg       QueueConfig
+	}{
+		{QueueSoloDuo, regionCfg.SoloDuo},
+		{QueueFlex, regionCfg.Flex},
+	} {
+		if !queue.cfg.IsEnabled() {
+			continue
+		}
+		for _, league := range queue.cfg.Leagues() {
+			leagueTypes = append(leagueTypes, leagueFetch{league, queue.queueType})
+		}
+	}
+
+	resultChan := make(chan leagueDataResult, len(leagueTypes))
+	var wg sync.WaitGroup
+
+	for _, leagueFetch := range leagueTypes {
+		wg.Add(1)
+		go func(leagueType, queueType string) {
+			defer wg.Done()
+			start := time.Now()
+			resp, err := fetcher.Fetch(ctx, region, leagueType, queueType)
+			slog.Debug("Fetched league", "region", region, "queue", queueType, "tier", leagueType,
+				"latency_ms", time.Since(start).Milliseconds(), "entries", len(resp.Entries), "error", err)
+			resultChan <- leagueDataResult{LeagueType: leagueType, QueueType: queueType, Response: resp, Err: err}
+		}(leagueFetch.LeagueType, leagueFetch.QueueType)
+	}
+
+	wg.Wait()
+	close(resultChan)
+
+	fetchErrors := make(map[string]error)
+	leagueResponses := make(map[string]LeagueResponse)
+
+	for result := range resultChan {
+		key := result.QueueType + "_" + result.LeagueType
+		if result.Err != nil {
+			fetchErrors[key] = fmt.Errorf("fetch %s %s for %s failed: %w",
+				result.LeagueType, result.QueueType, region, result.Err)
+		} else {
+			if dropped := sanitizeEntries(&result.Response, opts); dropped > 0 {
+				slog.Warn("Dropped league entries with implausible LP", "region", region, "queue", result.QueueType,
+					"tier", result.LeagueType, "dropped", dropped, "min_lp", opts.MinLP, "max_lp", opts.MaxLP)
+			}
+			leagueResponses[key] = result.Response
+		}
+	}
+
+	var queueErrors []error
+
+	var soloCutoffs, flexCutoffs Cutoffs
+	var soloDegraded, flexDegraded []string
+	var err error
+	if regionCfg.SoloDuo.IsEnabled() {
+		if previous, ok := unchangedCutoffs(QueueSoloDuo, leagueResponses, regionCfg.SoloDuo, opts); ok {
+			slog.Debug("Leagues unchanged, reusing cutoffs", "region", region, "queue", QueueSoloDuo)
+			soloCutoffs = previous
+		} else if soloCutoffs, soloDegraded, err = queueCutoffs(QueueSoloDuo, leagueResponses, fetchErrors, regionCfg.SoloDuo, opts); err != nil {
+			queueErrors = append(queueErrors, &QueueError{QueueSoloDuo, err})
+		}
+	}
+	if regionCfg.Flex.IsEnabled() {
+		if previous, ok := unchangedCutoffs(QueueFlex, leagueResponses, regionCfg.Flex, opts); ok {
+			slog.Debug("Leagues unchanged, reusing cutoffs", "region", region, "queue", QueueFlex)
+			flexCutoffs = previous
+		} else if flexCutoffs, flexDegraded, err = queueCutoffs(QueueFlex, leagueResponses, fetchErrors, regionCfg.Flex, opts); err != nil {
+			queueErrors = append(queueErrors, &QueueError{QueueFlex, err})
+		}
+	}
+
+	if len(queueErrors) > 0 {
+		return RegionData{}, fmt.Errorf("errors fetching league data for region %s:\n%w", region, errors.Join(queueErrors...))
+	}
+
+	degraded := append(soloDegraded, flexDegraded...)
+	for _, tier := range degraded {
+		slog.Warn("Region degraded, continuing without tier", "region", region, "tier", tier, "error", fetchErrors[tier])
+	}
+
+	return RegionData{
+		RANKED_SOLO_5x5: soloCutoffs,
+		RANKED_FLEX_SR:  flexCutoffs,
+		Degraded:        degraded,
+	}, nil
+}
+
+// unchangedCutoffs returns the previous cutoffs of queueType, stamped with the
+// current time, when every league the queue fetches came back NotModified.
+// It reports false when there are no previous cutoffs to reuse.
+func unchangedCutoffs(queueType string, responses map[string]LeagueResponse, cutoffsConfig QueueConfig, opts Options) (Cutoffs, bool) {
+	if opts.Previous == nil {
+		return Cutoffs{}, false
+	}
+	previous := opts.Previous.RANKED_SOLO_5x5
+	if queueType == QueueFlex {
+		previous = opts.Previous.RANKED_FLEX_SR
+	}
+	if !previous.Computed() {
+		return Cutoffs{}, false
+	}
+	for _, league := range cutoffsConfig.Leagues() {
+		if resp, ok := responses[queueType+"_"+league]; !ok || !resp.NotModified {
+			return Cutoffs{}, false
+		}
+	}
+	previous.UpdatedAt = time.Now().UTC()
+	return previous, true
+}
+
+// queueCutoffs computes the cutoffs of a single queue from the fetched league
+// responses. Challenger and Grandmaster are always required; a failed Master
+// fetch is tolerated as long as the remaining ladder still covers every
+// Challenger and Grandmaster slot, in which case the Master tier is reported
+// as degraded instead of failing the queue. Tiers the queue doesn't fetch are
+// missing from the ladder, so cutoffs the remaining ladder can't reach are
+// provisional.
+func queueCutoffs(queueType string, responses map[string]LeagueResponse, fetchErrors map[string]error, cutoffsConfig QueueConfig, opts Options) (Cutoffs, []string, error) {
+	if opts.ChallengerOnly {
+		return challengerOnlyCutoffs(queueType, responses, fetchErrors, cutoffsConfig, opts)
+	}
+
+	var errs []error
+	for _, league := range []string{LeagueChallenger, LeagueGrandmaster} {
+		if err, ok := fetchErrors[queueType+"_"+league]; ok {
+			errs = append(errs, err)
+		}
+	}
+
+	masterKey := queueType + "_" + LeagueMaster
+	masterErr, masterMissing := fetchErrors[masterKey]
+
+	if len(errs) > 0 {
+		if masterMissing {
+			errs = append(errs, masterErr)
+		}
+		return Cutoffs{}, nil, errors.Join(errs...)
+	}
+
+	challengerLeague := responses[queueType+"_"+LeagueChallenger]
+	grandmasterLeague := responses[queueType+"_"+LeagueGrandmaster]
+	masterLeague := responses[masterKey]
+	ladder := CreateLadder(challengerLeague, grandmasterLeague, masterLeague)
+
+	var degraded []string
+	if masterMissing {
+		if len(ladder) < cutoffsConfig.Challenger+cutoffsConfig.Grandmaster {
+			return Cutoffs{}, nil, fmt.Errorf("%w (ladder without master has only %d entries)", masterErr, len(ladder))
+		}
+		degraded = append(degraded, masterKey)
+	}
+
+	cutoffs := CalculateCutoffs(ladder, cutoffsConfig)
+	cutoffs.Provisional = len(ladder) < opts.MinLadderSize ||
+		float64(len(ladder)) < opts.ProvisionalSlotRatio*float64(cutoffsConfig.Challenger) ||
+		!cutoffsConfig.FetchesLeague(LeagueGrandmaster) ||
+		!cutoffsConfig.FetchesLeague(LeagueMaster) && len(ladder) < cutoffsConfig.Challenger+cutoffsConfig.Grandmaster
+	cutoffs.UpdatedAt = time.Now().UTC()
+	cutoffs.Ladder.ChallengerEntries = len(challengerLeague.Entries)
+	cutoffs.Ladder.GrandmasterEntries = len(grandmasterLeague.Entries)
+	cutoffs.Ladder.MasterEntries = len(masterLeague.Entries)
+	cutoffs.ChallengerMeanLP, cutoffs.ChallengerMedianLP = challengerStats(ladder, cutoffsConfig.Challenger)
+	if opts.HistogramBucketWidth > 0 {
+		cutoffs.Histogram = lpHistogram(ladder, opts.HistogramBucketWidth)
+	}
+	if opts.BoundaryWindow > 0 {
+		cutoffs.ChallengerBoundaryWinRate = boundaryWinRate(ladder, cutoffs.Ladder.ChallengerIndex, opts.BoundaryWindow)
+		cutoffs.GrandmasterBoundaryWinRate = boundaryWinRate(ladder, cutoffs.Ladder.GrandmasterIndex, opts.BoundaryWindow)
+	}
+	if opts.Gaps {
+		cutoffs.ChallengerGap = cutoffGap(ladder, cutoffs.Ladder.ChallengerIndex)
+		cutoffs.GrandmasterGap = cutoffGap(ladder, cutoffs.Ladder.GrandmasterIndex)
+	}
+	if opts.TrackPlayers {
+		cutoffs.Players = TierPlayers{
+			Challenger:  playersAbove(ladder, cutoffs.Challenger),
+			Grandmaster: playersAbove(ladder, cutoffs.Grandmaster),
+		}
+	}
+	if opts.RetainLadder {
+		cutoffs.Entries = ladder
+	}
+	return cutoffs, degraded, nil
+}
+
+// challengerOnlyCutoffs computes the Challenger cutoff of a single queue from
+// its Challenger league alone.
+func challengerOnlyCutoffs(queueType string, responses map[string]LeagueResponse, fetchErrors map[string]error, cutoffsConfig QueueConfig, opts Options) (Cutoffs, []string, error) {
+	if err, ok := fetchErrors[queueType+"_"+LeagueChallenger]; ok {
+		return Cutoffs{}, nil, err
+	}
+
+	challengerLeague := responses[queueType+"_"+LeagueChallenger]
+	ladder := CreateLadder(challengerLeague, LeagueResponse{}, LeagueResponse{})
+	cutoffs := CalculateCutoffs(ladder, cutoffsConfig)
+	cutoffs.Grandmaster = 0
+	cutoffs.Ladder.GrandmasterIndex = nil
+	cutoffs.ChallengerOnly = true
+	cutoffs.Provisional = len(ladder) < opts.MinLadderSize
+	cutoffs.UpdatedAt = time.Now().UTC()
+	cutoffs.Ladder.ChallengerEntries = len(challengerLeague.Entries)
+	if opts.TrackPlayers {
+		cutoffs.Players = TierPlayers{Challenger: playersAbove(ladder, cutoffs.Challenger)}
+	}
+	if opts.RetainLadder {
+		cutoffs.Entries = ladder
+	}
+	return cutoffs, nil, nil
+}
+
+// sanitizeEntries drops the entries of resp whose LP lies outside
+// [opts.MinLP, opts.MaxLP] and returns how many it dropped. The entries are
+// copied rather than filtered in place, since fetchers may cache responses.
+func sanitizeEntries(resp *LeagueResponse, opts Options) int {
+	if opts.MaxLP <= 0 {
+		return 0
+	}
+	kept := make([]LeagueEntry, 0, len(resp.Entries))
+	for _, entry := range resp.Entries {
+		if entry.LeaguePoints >= opts.MinLP && entry.LeaguePoints <= opts.MaxLP {
+			kept = append(kept, entry)
+		}
+	}
+	dropped := len(resp.Entries) - len(kept)
+	if dropped > 0 {
+		resp.Entries = kept
+	}
+	return dropped
+}
+
+// CreateLadder merges the three apex leagues into a single ladder sorted by
+// LP, highest first. A player listed more than once, as happens when paged
+// responses shift between requests, is kept only once. The league responses
+// are left untouched.
+func CreateLadder(challengerLeague, grandmasterLeague, masterLeague LeagueResponse) []LeagueEntry {
+	ladder := make([]LeagueEntry, 0, len(challengerLeague.Entries)+len(grandmasterLeague.Entries)+len(masterLeague.Entries))
+	seen := make(map[string]bool, cap(ladder))
+	for _, league := range []LeagueResponse{challengerLeague, grandmasterLeague, masterLeague} {
+		for _, entry := range league.Entries {
+			if id := entry.PlayerID(); id != "" {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			ladder = append(ladder, entry)
+		}
+	}
+	sort.Slice(ladder, func(i, j int) bool {
+		return ladder[i].LeaguePoints > ladder[j].LeaguePoints
+	})
+	return ladder
+}
+
+// CalculateCutoffs derives the Challenger and Grandmaster cutoffs from a
+// ladder sorted by LP, highest first.
+//
+// With C Challenger slots and G Grandmaster slots, the Challenger cutoff is the
+// LP of the player ranked C (ladder[C-1]) and the Grandmaster cutoff the LP of
+// the player ranked C+G (ladder[C+G-1]). Each cutoff is clamped to its tier's
+// minimum LP, and falls back to that minimum when the ladder has fewer players
+// than the rank it needs, since every player then qualifies by LP alone. The
+// minimums default to DefaultMinChallengerLP and DefaultMinGrandmasterLP
+// unless the queue overrides them.
+//
+// A queue selecting CutoffRank ignores the minimums: its cutoffs are the LP at
+// those ranks as is, and the LP of the last player on a ladder too short to
+// reach them, or 0 for an empty ladder.
+//
+// A queue sizing its tiers by percentile reads the cutoffs at the fractional
+// ranks the percentiles land on, see QueueConfig.ChallengerPercentile; the
+// interpolated LP is clamped to the minimums like any other.
+func CalculateCutoffs(ladder []LeagueEntry, cutoffsConfig QueueConfig) Cutoffs {
+	challengerFloor := cutoffsConfig.ChallengerFloor()
+	grandmasterFloor := cutoffsConfig.GrandmasterFloor()
+	if !cutoffsConfig.UsesFloors() {
+		// Nobody on a ladder sorted by LP has less than its last player, so
+		// clamping to that LP leaves the ranked LP as is.
+		lowest := 0
+		if len(ladder) > 0 {
+			lowest = ladder[len(ladder)-1].LeaguePoints
+		}
+		challengerFloor, grandmasterFloor = lowest, lowest
+	}
+	challenger := challengerFloor
+	grandmaster := grandmasterFloor
+
+	challengerRank, grandmasterRank := cutoffsConfig.ranks(len(ladder))
+	info := LadderInfo{Size: len(ladder)}
+
+	if lp, ok := LPAtRank(ladder, challengerRank, cutoffsConfig.Interpolate); ok {
+		challenger = max(challengerFloor, int(math.Round(lp)))
+		index := int(challengerRank) - 1
+		info.ChallengerIndex = &index
+	}
+	if lp, ok := LPAtRank(ladder, grandmasterRank, cutoffsConfig.Interpolate); ok {
+		grandmaster = max(grandmasterFloor, int(math.Round(lp)))
+		index := int(grandmasterRank) - 1
+		info.GrandmasterIndex = &index
+	}
+
+	return Cutoffs{
+		Challenger:  challenger,
+		Grandmaster: grandmaster,
+		Ladder:      info,
+	}
+}
+
+// ranks returns the 1-based ladder ranks of the last Challenger and the last
+// Grandmaster on a ladder of size players: the slot counts, or the fractional
+// ranks of the percentiles when set.
+func (q QueueConfig) ranks(size int) (challenger, grandmaster float64) {
+	challenger = float64(q.Challenger)
+	if q.ChallengerPercentile > 0 {
+		challenger = max(q.ChallengerPercentile/100*float64(size), 1)
+	}
+	grandmaster = challenger + float64(q.Grandmaster)
+	if q.GrandmasterPercentile > 0 {
+		grandmaster = challenger + q.GrandmasterPercentile/100*float64(size)
+	}
+	return challenger, grandmaster
+}
+
+// LPAtRank returns the LP at the 1-based, possibly fractional, rank of a
+// ladder sorted by LP, highest first. A fractional rank is truncated, or with
+// interpolate the LP is interpolated linearly between the ranks around it.
+// It reports false when rank lies outside the ladder.
+func LPAtRank(ladder []LeagueEntry, rank float64, interpolate bool) (float64, bool) {
+	if rank < 1 || rank >= float64(len(ladder)+1) {
+		return 0, false
+	}
+	whole := math.Floor(rank)
+	index := int(whole) - 1
+	lp := float64(ladder[index].LeaguePoints)
+	if frac := rank - whole; interpolate && frac > 0 && index+1 < len(ladder) {
+		lp += frac * float64(ladder[index+1].LeaguePoints-ladder[index].LeaguePoints)
+	}
+	return lp, true
+}
+
+// challengerStats returns the mean and median LP of the Challenger players,
+// i.e. the first slots entries of ladder sorted by LP, highest first. Both are
+// nil when the tier is empty.
+func challengerStats(ladder []LeagueEntry, slots int) (mean, median *float64) {
+	top := ladder[:min(slots, len(ladder))]
+	if len(top) == 0 {
+		return nil, nil
+	}
+
+	total := 0
+	for _, entry := range top {
+		total += entry.LeaguePoints
+	}
+	meanLP := float64(total) / float64(len(top))
+
+	// top is sorted descending, so the middle element(s) are the median.
+	mid := len(top) / 2
+	medianLP := float64(top[mid].LeaguePoints)
+	if len(top)%2 == 0 {
+		medianLP = float64(top[mid-1].LeaguePoints+top[mid].LeaguePoints) / 2
+	}
+	return &meanLP, &medianLP
+}
+
+// boundaryWinRate returns the mean win rate of the players ranked within
+// window ranks of ladder[*index]. Players without games are left out, and the
+// result is nil when the cutoff fell back to its floor or nobody in the
+// window has played.
+func boundaryWinRate(ladder []LeagueEntry, index *int, window int) *float64 {
+	if index == nil {
+		return nil
+	}
+
+	total, players := 0.0, 0
+	for _, entry := range ladder[max(*index-window, 0):min(*index+window+1, len(ladder))] {
+		games := entry.Wins + entry.Losses
+		if games <= 0 {
+			continue
+		}
+		total += float64(entry.Wins) / float64(games)
+		players++
+	}
+	if players == 0 {
+		return nil
+	}
+	winRate := total / float64(players)
+	return &winRate
+}
+
+// cutoffGap returns how much more LP ladder[*index], the last player inside a
+// tier, has than the player ranked right below. It is nil when the cutoff fell
+// back to its floor or nobody is ranked below.
+func cutoffGap(ladder []LeagueEntry, index *int) *int {
+	if index == nil || *index+1 >= len(ladder) {
+		return nil
+	}
+	gap := ladder[*index].LeaguePoints - ladder[*index+1].LeaguePoints
+	return &gap
+}
+
+// playersAbove returns the IDs of the players in ladder, sorted by LP highest
+// first, with at least lp LP. Entries without an ID are left out.
+func playersAbove(ladder []LeagueEntry, lp int) map[string]bool {
+	players := make(map[string]bool)
+	for _, entry := range ladder {
+		if entry.LeaguePoints < lp {
+			break
+		}
+		if id := entry.PlayerID(); id != "" {
+			players[id] = true
+		}
+	}
+	return players
+}
+
+// lpHistogram buckets the entries of ladder, sorted by LP highest first, into
+// consecutive ranges of width LP starting at 0. Empty buckets below the
+// highest LP are kept so the result can be plotted directly.
+func lpHistogram(ladder []LeagueEntry, width int) []HistogramBucket {
+	if len(ladder) == 0 {
+		return nil
+	}
+
+	buckets := make([]HistogramBucket, max(ladder[0].LeaguePoints, 0)/width+1)
+	for i := range buckets {
+		buckets[i] = HistogramBucket{MinLP: i * width, MaxLP: (i + 1) * width}
+	}
+	for _, entry := range ladder {
+		buckets[max(entry.LeaguePoints, 0)/width].Count++
+	}
+	return buckets
+}
@@ -0,0 +1,594 @@
+package cutoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// league returns a league response with one entry per LP value, each with a
+// distinct player ID.
+func league(prefix string, lps ...int) LeagueResponse {
+	entries := make([]LeagueEntry, len(lps))
+	for i, lp := range lps {
+		entries[i] = LeagueEntry{PUUID: fmt.Sprintf("%s-%d", prefix, i), LeaguePoints: lp}
+	}
+	return LeagueResponse{Entries: entries}
+}
+
+// descending returns n LP values counting down from top by step.
+func descending(top, step, n int) []int {
+	lps := make([]int, n)
+	for i := range lps {
+		lps[i] = top - i*step
+	}
+	return lps
+}
+
+func TestQueueCutoffsToleratesMissingMaster(t *testing.T) {
+	cfg := QueueConfig{Challenger: 3, Grandmaster: 5}
+	masterErr := errors.New("master timed out")
+
+	tests := []struct {
+		name         string
+		challenger   LeagueResponse
+		grandmaster  LeagueResponse
+		wantErr      bool
+		wantDegraded []string
+		wantCutoffs  [2]int
+	}{
+		{
+			name:         "ladder still covers every slot",
+			challenger:   league("c", 1500, 1400, 1300),
+			grandmaster:  league("gm", 900, 800, 700, 600, 550),
+			wantDegraded: []string{QueueSoloDuo + "_" + LeagueMaster},
+			wantCutoffs:  [2]int{1300, 550},
+		},
+		{
+			name:         "ladder larger than the slots",
+			challenger:   league("c", 1500, 1400, 1300, 1250),
+			grandmaster:  league("gm", 900, 800, 700, 600, 550, 520),
+			wantDegraded: []string{QueueSoloDuo + "_" + LeagueMaster},
+			wantCutoffs:  [2]int{1300, 600},
+		},
+		{
+			name:        "ladder too short without master",
+			challenger:  league("c", 1500, 1400, 1300),
+			grandmaster: league("gm", 900, 800),
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := map[string]LeagueResponse{
+				QueueSoloDuo + "_" + LeagueChallenger:  tt.challenger,
+				QueueSoloDuo + "_" + LeagueGrandmaster: tt.grandmaster,
+			}
+			fetchErrors := map[string]error{QueueSoloDuo + "_" + LeagueMaster: masterErr}
+
+			cutoffs, degraded, err := queueCutoffs(QueueSoloDuo, responses, fetchErrors, cfg, Options{})
+			if tt.wantErr {
+				if !errors.Is(err, masterErr) {
+					t.Fatalf("err = %v, want it to wrap %v", err, masterErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(degraded, tt.wantDegraded) {
+				t.Errorf("degraded = %v, want %v", degraded, tt.wantDegraded)
+			}
+			if got := [2]int{cutoffs.Challenger, cutoffs.Grandmaster}; got != tt.wantCutoffs {
+				t.Errorf("cutoffs = %v, want %v", got, tt.wantCutoffs)
+			}
+		})
+	}
+}
+
+func TestQueueCutoffsRequiresChallengerAndGrandmaster(t *testing.T) {
+	cfg := QueueConfig{Challenger: 1, Grandmaster: 1}
+	for _, league := range []string{LeagueChallenger, LeagueGrandmaster} {
+		t.Run(league, func(t *testing.T) {
+			fetchErr := errors.New("fetch failed")
+			fetchErrors := map[string]error{QueueSoloDuo + "_" + league: fetchErr}
+			if _, _, err := queueCutoffs(QueueSoloDuo, map[string]LeagueResponse{}, fetchErrors, cfg, Options{}); !errors.Is(err, fetchErr) {
+				t.Errorf("err = %v, want it to wrap %v", err, fetchErr)
+			}
+		})
+	}
+}
+
+// fakeFetcher serves canned league responses keyed by fetchKey and records
+// which leagues were requested.
+type fakeFetcher struct {
+	leagues map[string]LeagueResponse
+	errs    map[string]error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func fetchKey(region, league, queueType string) string {
+	return region + "/" + queueType + "/" + league
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, region, league, queueType string) (LeagueResponse, error) {
+	key := fetchKey(region, league, queueType)
+	f.mu.Lock()
+	f.calls = append(f.calls, key)
+	f.mu.Unlock()
+	if err := f.errs[key]; err != nil {
+		return LeagueResponse{}, err
+	}
+	return f.leagues[key], nil
+}
+
+// fetched returns the requested leagues, sorted.
+func (f *fakeFetcher) fetched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Sorted(slices.Values(f.calls))
+}
+
+// apexLeagues returns the fixtures of both queues of region: 3 Challengers,
+// 4 Grandmasters and 5 Masters, with LP counting down by 100 from 1500.
+func apexLeagues(region string) map[string]LeagueResponse {
+	leagues := make(map[string]LeagueResponse)
+	for _, queue := range []string{QueueSoloDuo, QueueFlex} {
+		leagues[fetchKey(region, LeagueChallenger, queue)] = league("c", 1500, 1400, 1300)
+		leagues[fetchKey(region, LeagueGrandmaster, queue)] = league("gm", 1200, 1100, 1000, 900)
+		leagues[fetchKey(region, LeagueMaster, queue)] = league("m", 800, 700, 600, 500, 400)
+	}
+	return leagues
+}
+
+func lps(ladder []LeagueEntry) []int {
+	out := make([]int, len(ladder))
+	for i, entry := range ladder {
+		out[i] = entry.LeaguePoints
+	}
+	return out
+}
+
+func TestCreateLadder(t *testing.T) {
+	tests := []struct {
+		name                            string
+		challenger, grandmaster, master LeagueResponse
+		want                            []int
+	}{
+		{"empty", LeagueResponse{}, LeagueResponse{}, LeagueResponse{}, []int{}},
+		{
+			"merged and sorted highest first",
+			league("c", 1300, 1500), league("gm", 900, 1100), league("m", 400, 600),
+			[]int{1500, 1300, 1100, 900, 600, 400},
+		},
+		{
+			"Grandmaster above a Challenger",
+			league("c", 1000), league("gm", 1050), LeagueResponse{},
+			[]int{1050, 1000},
+		},
+		{
+			"player listed twice is kept once",
+			league("c", 1500, 1400), league("c", 1500), LeagueResponse{},
+			[]int{1500, 1400},
+		},
+		{
+			"entries without ID are all kept",
+			LeagueResponse{Entries: []LeagueEntry{{LeaguePoints: 800}, {LeaguePoints: 800}}}, LeagueResponse{}, LeagueResponse{},
+			[]int{800, 800},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challengerBefore := slices.Clone(tt.challenger.Entries)
+			ladder := CreateLadder(tt.challenger, tt.grandmaster, tt.master)
+			if got := lps(ladder); !slices.Equal(got, tt.want) {
+				t.Errorf("ladder LP = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(tt.challenger.Entries, challengerBefore) {
+				t.Errorf("CreateLadder modified the Challenger league: %v", tt.challenger.Entries)
+			}
+		})
+	}
+}
+
+func TestCalculateCutoffs(t *testing.T) {
+	tests := []struct {
+		name            string
+		ladder          []int
+		cfg             QueueConfig
+		wantChallenger  int
+		wantGrandmaster int
+	}{
+		{"cutoffs at the last slot of each tier", descending(1500, 100, 10), QueueConfig{Challenger: 3, Grandmaster: 4}, 1300, 900},
+		{"ties at the boundary", []int{1000, 900, 900, 900, 800}, QueueConfig{Challenger: 2, Grandmaster: 2}, 900, 900},
+		{"one slot each", []int{700, 600}, QueueConfig{Challenger: 1, Grandmaster: 1}, 700, 600},
+		{"rank semantics skip the floors", []int{450, 300, 150}, QueueConfig{Challenger: 1, Grandmaster: 2, Cutoff: CutoffRank}, 450, 150},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateCutoffs(league("p", tt.ladder...).Entries, tt.cfg)
+			if got.Challenger != tt.wantChallenger || got.Grandmaster != tt.wantGrandmaster {
+				t.Errorf("cutoffs = %d/%d, want %d/%d", got.Challenger, got.Grandmaster, tt.wantChallenger, tt.wantGrandmaster)
+			}
+		})
+	}
+}
+
+func TestComputeRegion(t *testing.T) {
+	queues := Queues{
+		SoloDuo: QueueConfig{Challenger: 3, Grandmaster: 4},
+		Flex:    QueueConfig{Challenger: 2, Grandmaster: 2},
+	}
+	gmErr := errors.New("grandmaster unavailable")
+
+	tests := []struct {
+		name     string
+		errs     map[string]error
+		wantErr  error
+		wantSolo [2]int
+		wantFlex [2]int
+	}{
+		{name: "every league fetched", wantSolo: [2]int{1300, 900}, wantFlex: [2]int{1400, 1200}},
+		{
+			name:    "required league failed",
+			errs:    map[string]error{fetchKey("euw1", LeagueGrandmaster, QueueFlex): gmErr},
+			wantErr: gmErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &fakeFetcher{leagues: apexLeagues("euw1"), errs: tt.errs}
+			data, err := ComputeRegion(context.Background(), fetcher, "euw1", queues, Options{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want it to wrap %v", err, tt.wantErr)
+				}
+				if queueErrs := QueueErrors(err); len(queueErrs) != 1 || queueErrs[0].Queue != QueueFlex {
+					t.Errorf("queue errors = %v, want only %s", queueErrs, QueueFlex)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := [2]int{data.RANKED_SOLO_5x5.Challenger, data.RANKED_SOLO_5x5.Grandmaster}; got != tt.wantSolo {
+				t.Errorf("solo/duo cutoffs = %v, want %v", got, tt.wantSolo)
+			}
+			if got := [2]int{data.RANKED_FLEX_SR.Challenger, data.RANKED_FLEX_SR.Grandmaster}; got != tt.wantFlex {
+				t.Errorf("flex cutoffs = %v, want %v", got, tt.wantFlex)
+			}
+			if len(fetcher.fetched()) != 6 {
+				t.Errorf("fetched %v, want all six leagues", fetcher.fetched())
+			}
+		})
+	}
+}
+
+func TestComputeRegionSeasonReset(t *testing.T) {
+	queues := Queues{
+		SoloDuo: QueueConfig{Challenger: 3, Grandmaster: 4},
+		Flex:    QueueConfig{Challenger: 3, Grandmaster: 4},
+	}
+	tests := []struct {
+		name            string
+		challenger      LeagueResponse
+		wantProvisional bool
+		wantChallenger  int
+	}{
+		{"empty leagues", LeagueResponse{}, true, DefaultMinChallengerLP},
+		{"ladder below the minimum size", league("c", 900, 800), true, DefaultMinChallengerLP},
+		{"ladder at the minimum size", league("c", 1500, 1400, 1300, 1200, 1100), false, 1300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leagues := make(map[string]LeagueResponse)
+			for _, queue := range []string{QueueSoloDuo, QueueFlex} {
+				leagues[fetchKey("euw1", LeagueChallenger, queue)] = tt.challenger
+			}
+			fetcher := &fakeFetcher{leagues: leagues}
+			data, err := ComputeRegion(context.Background(), fetcher, "euw1", queues, Options{MinLadderSize: 5})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, c := range []Cutoffs{data.RANKED_SOLO_5x5, data.RANKED_FLEX_SR} {
+				if c.Provisional != tt.wantProvisional {
+					t.Errorf("provisional = %v, want %v", c.Provisional, tt.wantProvisional)
+				}
+				if c.Challenger != tt.wantChallenger {
+					t.Errorf("Challenger cutoff = %d, want %d", c.Challenger, tt.wantChallenger)
+				}
+			}
+		})
+	}
+}
+
+func TestChallengerStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		ladder     []int
+		slots      int
+		wantMean   float64
+		wantMedian float64
+		wantEmpty  bool
+	}{
+		{name: "odd slots", ladder: []int{1500, 1200, 900, 100}, slots: 3, wantMean: 1200, wantMedian: 1200},
+		{name: "even slots", ladder: []int{2000, 1000, 900, 500, 100}, slots: 4, wantMean: 1100, wantMedian: 950},
+		{name: "top-heavy", ladder: []int{3000, 1000, 1000}, slots: 3, wantMean: 5000.0 / 3, wantMedian: 1000},
+		{name: "fewer players than slots", ladder: []int{800, 600}, slots: 5, wantMean: 700, wantMedian: 700},
+		{name: "empty tier", slots: 3, wantEmpty: true},
+		{name: "no slots", ladder: []int{800}, slots: 0, wantEmpty: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mean, median := challengerStats(league("p", tt.ladder...).Entries, tt.slots)
+			if tt.wantEmpty {
+				if mean != nil || median != nil {
+					t.Errorf("mean, median = %v, %v, want nil for an empty tier", mean, median)
+				}
+				return
+			}
+			if mean == nil || median == nil {
+				t.Fatalf("mean, median = %v, %v, want both set", mean, median)
+			}
+			if *mean != tt.wantMean || *median != tt.wantMedian {
+				t.Errorf("mean, median = %g, %g, want %g, %g", *mean, *median, tt.wantMean, tt.wantMedian)
+			}
+		})
+	}
+}
+
+func TestQueueCutoffsProvisionalThreshold(t *testing.T) {
+	cfg := QueueConfig{Challenger: 10, Grandmaster: 2}
+	tests := []struct {
+		name            string
+		ladderSize      int
+		ratio           float64
+		wantProvisional bool
+	}{
+		{"fewer players than Challenger slots", 8, 1, true},
+		{"as many players as Challenger slots", 10, 1, false},
+		{"ladder covers every slot", 12, 1, false},
+		{"below a stricter ratio", 12, 1.5, true},
+		{"at a stricter ratio", 15, 1.5, false},
+		{"ratio disabled", 8, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := map[string]LeagueResponse{
+				QueueFlex + "_" + LeagueChallenger: league("c", descending(1500, 10, tt.ladderSize)...),
+			}
+			cutoffs, _, err := queueCutoffs(QueueFlex, responses, nil, cfg, Options{ProvisionalSlotRatio: tt.ratio})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cutoffs.Provisional != tt.wantProvisional {
+				t.Errorf("provisional = %v, want %v", cutoffs.Provisional, tt.wantProvisional)
+			}
+		})
+	}
+}
+
+func TestComputeRegionSkipsDisabledQueue(t *testing.T) {
+	disabled := false
+	queues := Queues{
+		SoloDuo: QueueConfig{Challenger: 3, Grandmaster: 4},
+		Flex:    QueueConfig{Enabled: &disabled, Challenger: 2, Grandmaster: 2},
+	}
+	fetcher := &fakeFetcher{leagues: apexLeagues("euw1")}
+	data, err := ComputeRegion(context.Background(), fetcher, "euw1", queues, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		fetchKey("euw1", LeagueChallenger, QueueSoloDuo),
+		fetchKey("euw1", LeagueGrandmaster, QueueSoloDuo),
+		fetchKey("euw1", LeagueMaster, QueueSoloDuo),
+	}
+	if got := fetcher.fetched(); !slices.Equal(got, slices.Sorted(slices.Values(want))) {
+		t.Errorf("fetched %v, want only the solo/duo leagues", got)
+	}
+	if data.RANKED_FLEX_SR.Computed() {
+		t.Errorf("disabled flex queue was computed: %+v", data.RANKED_FLEX_SR)
+	}
+	if !data.RANKED_SOLO_5x5.Computed() {
+		t.Error("solo/duo queue wasn't computed")
+	}
+}
+
+func TestBoundaryWinRate(t *testing.T) {
+	// Wins and losses of a ladder of seven players; the cutoff sits at
+	// index 3.
+	records := [][2]int{{90, 10}, {60, 40}, {30, 10}, {50, 50}, {0, 0}, {25, 75}, {10, 90}}
+	ladder := make([]LeagueEntry, len(records))
+	for i, r := range records {
+		ladder[i] = LeagueEntry{PUUID: fmt.Sprint(i), LeaguePoints: 1000 - 10*i, Wins: r[0], Losses: r[1]}
+	}
+
+	tests := []struct {
+		name   string
+		ladder []LeagueEntry
+		index  *int
+		window int
+		want   *float64
+	}{
+		{"cutoff player alone", ladder, ptr(3), 0, ptr(0.5)},
+		{"players without games are left out", ladder, ptr(3), 1, ptr((0.75 + 0.5) / 2)},
+		{"window clipped at the top", ladder, ptr(0), 2, ptr((0.9 + 0.6 + 0.75) / 3)},
+		{"window clipped at the bottom", ladder, ptr(6), 1, ptr((0.25 + 0.1) / 2)},
+		{"cutoff at its floor", ladder, nil, 2, nil},
+		{"nobody played", ladder, ptr(4), 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := boundaryWinRate(tt.ladder, tt.index, tt.window)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil:
+				t.Errorf("win rate = %v, want %v", got, tt.want)
+			case math.Abs(*got-*tt.want) > 1e-9:
+				t.Errorf("win rate = %g, want %g", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestCutoffGap(t *testing.T) {
+	ladder := league("p", 1500, 1450, 1450, 1449, 1200).Entries
+	tests := []struct {
+		name  string
+		index *int
+		want  *int
+	}{
+		{"gap to the next rank", ptr(0), ptr(50)},
+		{"tie", ptr(1), ptr(0)},
+		{"one LP", ptr(2), ptr(1)},
+		{"last player of the ladder", ptr(4), nil},
+		{"cutoff at its floor", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cutoffGap(ladder, tt.index)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil:
+				t.Errorf("gap = %v, want %v", got, tt.want)
+			case *got != *tt.want:
+				t.Errorf("gap = %d, want %d", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueCutoffsGaps(t *testing.T) {
+	responses := map[string]LeagueResponse{
+		QueueSoloDuo + "_" + LeagueChallenger:  league("c", 1500, 1400, 1390),
+		QueueSoloDuo + "_" + LeagueGrandmaster: league("gm", 1390, 1000, 990),
+	}
+	cfg := QueueConfig{Challenger: 2, Grandmaster: 3}
+	cutoffs, _, err := queueCutoffs(QueueSoloDuo, responses, nil, cfg, Options{Gaps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cutoffs.ChallengerGap == nil || *cutoffs.ChallengerGap != 10 {
+		t.Errorf("Challenger gap = %v, want 10", cutoffs.ChallengerGap)
+	}
+	if cutoffs.GrandmasterGap == nil || *cutoffs.GrandmasterGap != 10 {
+		t.Errorf("Grandmaster gap = %v, want 10", cutoffs.GrandmasterGap)
+	}
+
+	withoutGaps, _, err := queueCutoffs(QueueSoloDuo, responses, nil, cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withoutGaps.ChallengerGap != nil || withoutGaps.GrandmasterGap != nil {
+		t.Error("gaps computed without Options.Gaps")
+	}
+}
+
+func TestComputeRegionDropsImplausibleLP(t *testing.T) {
+	queues := Queues{
+		SoloDuo: QueueConfig{Challenger: 3, Grandmaster: 4},
+		Flex:    QueueConfig{Challenger: 3, Grandmaster: 4},
+	}
+	tests := []struct {
+		name     string
+		opts     Options
+		wantSolo [2]int
+	}{
+		{"out-of-range LP excluded", Options{MinLP: 0, MaxLP: 5000}, [2]int{1300, 900}},
+		{"disabled", Options{}, [2]int{1400, 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leagues := apexLeagues("euw1")
+			leagues[fetchKey("euw1", LeagueChallenger, QueueSoloDuo)] = league("c", 99999, 1500, 1400, 1300)
+			leagues[fetchKey("euw1", LeagueMaster, QueueSoloDuo)] = league("m", 800, 700, 600, 500, 400, -20)
+			fetcher := &fakeFetcher{leagues: leagues}
+
+			data, err := ComputeRegion(context.Background(), fetcher, "euw1", queues, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := [2]int{data.RANKED_SOLO_5x5.Challenger, data.RANKED_SOLO_5x5.Grandmaster}; got != tt.wantSolo {
+				t.Errorf("solo/duo cutoffs = %v, want %v", got, tt.wantSolo)
+			}
+			if got := [2]int{data.RANKED_FLEX_SR.Challenger, data.RANKED_FLEX_SR.Grandmaster}; got != [2]int{1300, 900} {
+				t.Errorf("flex cutoffs = %v, want [1300 900]", got)
+			}
+			// The fetcher's responses are left as they were.
+			if got := len(leagues[fetchKey("euw1", LeagueChallenger, QueueSoloDuo)].Entries); got != 4 {
+				t.Errorf("fetched Challenger league has %d entries, want the 4 served", got)
+			}
+		})
+	}
+}
+
+func TestSanitizeEntries(t *testing.T) {
+	resp := league("c", 5001, 5000, 1200, 0, -1)
+	if dropped := sanitizeEntries(&resp, Options{MinLP: 0, MaxLP: 5000}); dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+	if got := lps(resp.Entries); !slices.Equal(got, []int{5000, 1200, 0}) {
+		t.Errorf("kept LP = %v, want the bounds inclusive [5000 1200 0]", got)
+	}
+}
+
+func TestComputeRegionTierSubsets(t *testing.T) {
+	tests := []struct {
+		name            string
+		tiers           []string
+		grandmaster     int
+		wantFetched     []string
+		wantCutoffs     [2]int
+		wantProvisional bool
+	}{
+		{"all tiers by default", nil, 4, []string{LeagueChallenger, LeagueGrandmaster, LeagueMaster}, [2]int{1300, 900}, false},
+		{"without master", []string{TierChallenger, TierGrandmaster}, 4, []string{LeagueChallenger, LeagueGrandmaster}, [2]int{1300, 900}, false},
+		{"without master too short", []string{TierChallenger, TierGrandmaster}, 5, []string{LeagueChallenger, LeagueGrandmaster}, [2]int{1300, DefaultMinGrandmasterLP}, true},
+		{"without grandmaster", []string{TierChallenger, TierMaster}, 4, []string{LeagueChallenger, LeagueMaster}, [2]int{1300, 500}, true},
+		{"challenger only", []string{TierChallenger}, 4, []string{LeagueChallenger}, [2]int{1300, DefaultMinGrandmasterLP}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queues := Queues{
+				SoloDuo: QueueConfig{Challenger: 3, Grandmaster: tt.grandmaster, Tiers: tt.tiers},
+				Flex:    QueueConfig{Challenger: 3, Grandmaster: 4},
+			}
+			fetcher := &fakeFetcher{leagues: apexLeagues("euw1")}
+			data, err := ComputeRegion(context.Background(), fetcher, "euw1", queues, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want []string
+			for _, league := range tt.wantFetched {
+				want = append(want, fetchKey("euw1", league, QueueSoloDuo))
+			}
+			for _, league := range []string{LeagueChallenger, LeagueGrandmaster, LeagueMaster} {
+				want = append(want, fetchKey("euw1", league, QueueFlex))
+			}
+			slices.Sort(want)
+			if got := fetcher.fetched(); !slices.Equal(got, want) {
+				t.Errorf("fetched %v, want %v", got, want)
+			}
+			solo := data.RANKED_SOLO_5x5
+			if got := [2]int{solo.Challenger, solo.Grandmaster}; got != tt.wantCutoffs {
+				t.Errorf("solo/duo cutoffs = %v, want %v", got, tt.wantCutoffs)
+			}
+			if solo.Provisional != tt.wantProvisional {
+				t.Errorf("solo/duo provisional = %t, want %t", solo.Provisional, tt.wantProvisional)
+			}
+			if flex := data.RANKED_FLEX_SR; flex.Provisional || flex.Challenger != 1300 || flex.Grandmaster != 900 {
+				t.Errorf("flex cutoffs = %d/%d provisional %t, want 1300/900 from every tier", flex.Challenger, flex.Grandmaster, flex.Provisional)
+			}
+		})
+	}
+}
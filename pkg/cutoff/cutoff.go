@@ -0,0 +1,362 @@
+// Package cutoff computes the LP cutoffs of League of Legends' Challenger and
+// Grandmaster tiers from the apex league ladders.
+//
+// The ladder math works on league entries from any source:
+//
+//	ladder := cutoff.CreateLadder(challenger, grandmaster, master)
+//	cutoffs := cutoff.CalculateCutoffs(ladder, cutoff.QueueConfig{Challenger: 300, Grandmaster: 700})
+//
+// ComputeRegion does the fetching as well, through a Fetcher:
+//
+//	data, err := cutoff.ComputeRegion(ctx, fetcher, "euw1", queues, cutoff.Options{MinLadderSize: 10})
+package cutoff
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Cutoffs are the Challenger and Grandmaster cutoffs of one queue, in LP.
+type Cutoffs struct {
+	Challenger  int  `json:"challenger"`
+	Grandmaster int  `json:"grandmaster"`
+	Provisional bool `json:"provisional,omitempty"`
+	// ChallengerOnly marks cutoffs computed from the Challenger league alone;
+	// they have no Grandmaster cutoff, which is then omitted from the JSON.
+	ChallengerOnly bool `json:"challengerOnly,omitempty"`
+	// UpdatedAt is when the queue's cutoffs were last successfully computed.
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// ChallengerMeanLP and ChallengerMedianLP describe the LP of the players
+	// holding a Challenger slot; they are omitted when the tier is empty.
+	ChallengerMeanLP   *float64 `json:"challengerMeanLp,omitempty"`
+	ChallengerMedianLP *float64 `json:"challengerMedianLp,omitempty"`
+
+	Histogram []HistogramBucket `json:"histogram,omitempty"`
+
+	// ChallengerBoundaryWinRate and GrandmasterBoundaryWinRate are the mean
+	// win rate of the players ranked around each cutoff.
+	ChallengerBoundaryWinRate  *float64 `json:"challengerBoundaryWinRate,omitempty"`
+	GrandmasterBoundaryWinRate *float64 `json:"grandmasterBoundaryWinRate,omitempty"`
+
+	// ChallengerGap and GrandmasterGap are the LP separating the last player
+	// inside each tier from the first player below it; zero means they are
+	// tied.
+	ChallengerGap  *int `json:"challengerGap,omitempty"`
+	GrandmasterGap *int `json:"grandmasterGap,omitempty"`
+
+	// Ladder describes the ladder the cutoffs were computed from. It is only
+	// exposed through the debug endpoint.
+	Ladder LadderInfo `json:"-"`
+
+	// Players holds who is at or above each cutoff when Options.TrackPlayers
+	// is set.
+	Players TierPlayers `json:"-"`
+
+	// Entries is the ladder, sorted by LP highest first, when
+	// Options.RetainLadder is set.
+	Entries []LeagueEntry `json:"-"`
+}
+
+// MarshalJSON leaves the Grandmaster cutoff out of Challenger-only cutoffs.
+func (c Cutoffs) MarshalJSON() ([]byte, error) {
+	type plain Cutoffs
+	if !c.ChallengerOnly {
+		return json.Marshal(plain(c))
+	}
+	return json.Marshal(struct {
+		plain
+		Grandmaster *int `json:"grandmaster,omitempty"`
+	}{plain: plain(c)})
+}
+
+// TierPlayers are the IDs, as returned by LeagueEntry.PlayerID, of the
+// players whose LP is at or above the Challenger and the Grandmaster cutoff.
+type TierPlayers struct {
+	Challenger  map[string]bool
+	Grandmaster map[string]bool
+}
+
+// LadderInfo records the league and ladder sizes behind a queue's cutoffs and
+// the ladder indices the cutoffs were read from. An index is nil when the
+// ladder was too short and the tier's minimum LP was used instead.
+type LadderInfo struct {
+	ChallengerEntries  int  `json:"challengerEntries"`
+	GrandmasterEntries int  `json:"grandmasterEntries"`
+	MasterEntries      int  `json:"masterEntries"`
+	Size               int  `json:"ladderSize"`
+	ChallengerIndex    *int `json:"challengerIndex"`
+	GrandmasterIndex   *int `json:"grandmasterIndex"`
+}
+
+// Computed reports whether c holds computed cutoffs rather than being left
+// zero for a disabled queue.
+func (c Cutoffs) Computed() bool {
+	return !c.UpdatedAt.IsZero()
+}
+
+// HistogramBucket counts the ladder entries with MinLP <= LP < MaxLP.
+type HistogramBucket struct {
+	MinLP int `json:"minLp"`
+	MaxLP int `json:"maxLp"`
+	Count int `json:"count"`
+}
+
+// Options tunes how cutoffs are derived from a ladder.
+type Options struct {
+	// MinLadderSize is the smallest ladder considered large enough to yield
+	// genuine cutoffs; smaller ladders produce provisional cutoffs.
+	MinLadderSize int
+	// ProvisionalSlotRatio marks a queue's cutoffs provisional when its ladder
+	// has fewer entries than this fraction of the queue's Challenger slots,
+	// as happens in low-population Flex queues. Zero disables the check.
+	ProvisionalSlotRatio float64
+
+	// HistogramBucketWidth enables the LP distribution histogram with buckets
+	// of this many LP. Zero disables it.
+	HistogramBucketWidth int
+
+	// BoundaryWindow enables the boundary win rates, averaged over the
+	// players up to this many ranks above and below each cutoff. Zero
+	// disables them.
+	BoundaryWindow int
+
+	// Gaps enables the LP gaps at the cutoffs.
+	Gaps bool
+
+	// TrackPlayers records the players at or above each cutoff in
+	// Cutoffs.Players.
+	TrackPlayers bool
+
+	// RetainLadder keeps the ladder in Cutoffs.Entries.
+	RetainLadder bool
+
+	// MinLP and MaxLP bound the LP considered plausible; entries outside
+	// them are dropped before the ladder is built. A zero MaxLP disables the
+	// bounds.
+	MinLP int
+	MaxLP int
+
+	// ChallengerOnly fetches only the solo/duo Challenger league of a region,
+	// one request instead of up to six, and computes just its Challenger
+	// cutoff. Flex is skipped whatever the config says. The cutoff is exact as
+	// long as the Challenger league holds no more players than there are
+	// slots; beyond that it ignores Grandmaster players who outrank the last
+	// Challengers, which Riot only promotes at its next daily update anyway.
+	ChallengerOnly bool
+
+	// Previous is the region's result of the previous ComputeRegion call
+	// with the same config. A queue whose leagues all come back NotModified
+	// reuses its previous cutoffs instead of rebuilding the ladder.
+	Previous *RegionData
+}
+
+// Queues is the configuration of a region. The LP floors set here apply to
+// both queues unless a queue overrides them.
+type Queues struct {
+	SoloDuo QueueConfig `yaml:"solo_duo"`
+	Flex    QueueConfig `yaml:"flex"`
+
+	MinChallengerLP  *int `yaml:"min_challenger_lp,omitempty"`
+	MinGrandmasterLP *int `yaml:"min_grandmaster_lp,omitempty"`
+
+	// BaseURL overrides the API base URL used to fetch the region, e.g. to
+	// route it through a regional gateway. It is not used by this package.
+	BaseURL string `yaml:"base_url,omitempty"`
+	// PollInterval is the minimum time between two fetches of the region,
+	// e.g. to poll a quiet shard less often than the others. It is not used
+	// by this package.
+	PollInterval time.Duration `yaml:"poll_interval,omitempty"`
+}
+
+// QueueConfig is the configuration of one ranked queue in a region: the number
+// of Challenger and Grandmaster slots and optional overrides of the minimum LP
+// of each tier.
+type QueueConfig struct {
+	// Enabled turns the queue off when set to false; its leagues are then
+	// neither fetched nor published.
+	Enabled     *bool `yaml:"enabled,omitempty"`
+	Challenger  int   `yaml:"challenger"`
+	Grandmaster int   `yaml:"grandmaster"`
+
+	MinChallengerLP  *int `yaml:"min_challenger_lp,omitempty"`
+	MinGrandmasterLP *int `yaml:"min_grandmaster_lp,omitempty"`
+
+	// Cutoff selects how the cutoffs are read from the ladder, CutoffFloor
+	// unless set.
+	Cutoff string `yaml:"cutoff,omitempty"`
+
+	// Tiers lists the tiers whose leagues are fetched, all three unless set.
+	// Leaving tiers out saves requests at the cost of provisional cutoffs
+	// when the remaining ladder can't cover every slot.
+	Tiers []string `yaml:"tiers,omitempty"`
+
+	// ChallengerPercentile and GrandmasterPercentile, when set, size the
+	// tiers as a percentage of the ladder instead of by their slots, which
+	// still decide whether the ladder is complete. A percentile usually lands
+	// on a fractional rank, which is truncated unless Interpolate is set.
+	ChallengerPercentile  float64 `yaml:"challenger_percentile,omitempty"`
+	GrandmasterPercentile float64 `yaml:"grandmaster_percentile,omitempty"`
+	// Interpolate reads the LP at a fractional rank by interpolating linearly
+	// between the ranks around it, rounded to whole LP.
+	Interpolate bool `yaml:"interpolate,omitempty"`
+}
+
+// TierMaster names the Master tier in QueueConfig.Tiers.
+const TierMaster = "master"
+
+// tierLeagues maps the tiers to their leagues.
+var tierLeagues = map[string]string{
+	TierChallenger:  LeagueChallenger,
+	TierGrandmaster: LeagueGrandmaster,
+	TierMaster:      LeagueMaster,
+}
+
+// Leagues returns the leagues fetched for the queue, in tier order.
+func (q QueueConfig) Leagues() []string {
+	if len(q.Tiers) == 0 {
+		return []string{LeagueChallenger, LeagueGrandmaster, LeagueMaster}
+	}
+	var leagues []string
+	for _, tier := range []string{TierChallenger, TierGrandmaster, TierMaster} {
+		if slices.Contains(q.Tiers, tier) {
+			leagues = append(leagues, tierLeagues[tier])
+		}
+	}
+	return leagues
+}
+
+// FetchesLeague reports whether league is fetched for the queue.
+func (q QueueConfig) FetchesLeague(league string) bool {
+	return slices.Contains(q.Leagues(), league)
+}
+
+// The cutoff semantics a queue can select.
+const (
+	// CutoffFloor takes the LP of the player at each tier's last slot,
+	// clamped to the tier's minimum LP.
+	CutoffFloor = "floor"
+	// CutoffRank takes the LP of the player at each tier's last slot as is,
+	// even below the minimum LP, defining the tiers by player count alone.
+	CutoffRank = "rank"
+)
+
+// UsesFloors reports whether the queue's cutoffs are clamped to the tiers'
+// minimum LP.
+func (q QueueConfig) UsesFloors() bool {
+	return q.Cutoff != CutoffRank
+}
+
+// IsEnabled reports whether the queue is fetched, which it is unless the config
+// disables it.
+func (q QueueConfig) IsEnabled() bool {
+	return q.Enabled == nil || *q.Enabled
+}
+
+// ChallengerFloor returns the minimum Challenger LP of the queue.
+func (q QueueConfig) ChallengerFloor() int {
+	if q.MinChallengerLP != nil {
+		return *q.MinChallengerLP
+	}
+	return DefaultMinChallengerLP
+}
+
+// GrandmasterFloor returns the minimum Grandmaster LP of the queue.
+func (q QueueConfig) GrandmasterFloor() int {
+	if q.MinGrandmasterLP != nil {
+		return *q.MinGrandmasterLP
+	}
+	return DefaultMinGrandmasterLP
+}
+
+// LeagueEntry is a player in an apex league.
+type LeagueEntry struct {
+	PUUID        string `json:"puuid"`
+	SummonerID   string `json:"summonerId"`
+	LeaguePoints int    `json:"leaguePoints"`
+	Wins         int    `json:"wins"`
+	Losses       int    `json:"losses"`
+}
+
+// PlayerID identifies the player of e by PUUID, falling back to the summoner
+// ID. It is empty when Riot sent neither.
+func (e LeagueEntry) PlayerID() string {
+	if e.PUUID != "" {
+		return e.PUUID
+	}
+	return e.SummonerID
+}
+
+// LeagueResponse is an apex league as returned by Riot.
+type LeagueResponse struct {
+	Entries []LeagueEntry `json:"entries"`
+	// NotModified marks a response the fetcher revalidated instead of
+	// downloading, i.e. the league is unchanged since the previous fetch.
+	NotModified bool `json:"-"`
+}
+
+// The minimum LP of each tier, unless a queue overrides it.
+const (
+	DefaultMinChallengerLP  = 500
+	DefaultMinGrandmasterLP = 200
+)
+
+// The ranked queues.
+const (
+	QueueSoloDuo = "RANKED_SOLO_5x5"
+	QueueFlex    = "RANKED_FLEX_SR"
+)
+
+// The apex leagues, as named in Riot's league-v4 paths.
+const (
+	LeagueChallenger  = "challengerleagues"
+	LeagueGrandmaster = "grandmasterleagues"
+	LeagueMaster      = "masterleagues"
+)
+
+// The tiers a cutoff is computed for.
+const (
+	TierChallenger  = "challenger"
+	TierGrandmaster = "grandmaster"
+)
+
+// RegionData holds a region's cutoffs. A queue disabled in the config is left
+// zero and omitted from the JSON.
+type RegionData struct {
+	RANKED_SOLO_5x5 Cutoffs   `json:"RANKED_SOLO_5x5,omitzero"`
+	RANKED_FLEX_SR  Cutoffs   `json:"RANKED_FLEX_SR,omitzero"`
+	Degraded        []string  `json:"degraded,omitempty"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	Stale           bool      `json:"stale,omitempty"`
+}
+
+// Value is a single tier's cutoff in one queue.
+type Value struct {
+	Queue string
+	Tier  string
+	LP    int
+}
+
+// Values flattens d into one value per queue and tier, always in the
+// same order. Queues that were not computed are skipped, as are the
+// Grandmaster cutoffs of Challenger-only queues.
+func (d RegionData) Values() []Value {
+	var values []Value
+	for _, queue := range []struct {
+		queueType string
+		cutoffs   Cutoffs
+	}{
+		{QueueSoloDuo, d.RANKED_SOLO_5x5},
+		{QueueFlex, d.RANKED_FLEX_SR},
+	} {
+		if !queue.cutoffs.Computed() {
+			continue
+		}
+		values = append(values, Value{queue.queueType, TierChallenger, queue.cutoffs.Challenger})
+		if !queue.cutoffs.ChallengerOnly {
+			values = append(values, Value{queue.queueType, TierGrandmaster, queue.cutoffs.Grandmaster})
+		}
+	}
+	return values
+}
@@ -0,0 +1,156 @@
+package cutoff
+
+import "testing"
+
+func TestCalculateCutoffsBoundaries(t *testing.T) {
+	cfg := QueueConfig{Challenger: 3, Grandmaster: 4}
+
+	tests := []struct {
+		name             string
+		ladder           []int
+		cfg              QueueConfig
+		wantChallenger   int
+		wantGrandmaster  int
+		wantChallengerAt *int
+		wantGMAt         *int
+	}{
+		{
+			name:             "exactly C entries",
+			ladder:           []int{1500, 1400, 1300},
+			cfg:              cfg,
+			wantChallenger:   1300,
+			wantGrandmaster:  DefaultMinGrandmasterLP,
+			wantChallengerAt: ptr(2),
+		},
+		{
+			name:             "exactly C+G entries",
+			ladder:           descending(1500, 100, 7),
+			cfg:              cfg,
+			wantChallenger:   1300,
+			wantGrandmaster:  900,
+			wantChallengerAt: ptr(2),
+			wantGMAt:         ptr(6),
+		},
+		{
+			name:            "empty ladder",
+			cfg:             cfg,
+			wantChallenger:  DefaultMinChallengerLP,
+			wantGrandmaster: DefaultMinGrandmasterLP,
+		},
+		{
+			name:            "ladder shorter than C stays at the floors",
+			ladder:          []int{1500, 1400},
+			cfg:             cfg,
+			wantChallenger:  DefaultMinChallengerLP,
+			wantGrandmaster: DefaultMinGrandmasterLP,
+		},
+		{
+			name:             "LP below the floors is clamped",
+			ladder:           descending(450, 50, 7),
+			cfg:              cfg,
+			wantChallenger:   DefaultMinChallengerLP,
+			wantGrandmaster:  DefaultMinGrandmasterLP,
+			wantChallengerAt: ptr(2),
+			wantGMAt:         ptr(6),
+		},
+		{
+			name:             "LP below configured floors is clamped",
+			ladder:           descending(450, 50, 7),
+			cfg:              QueueConfig{Challenger: 3, Grandmaster: 4, MinChallengerLP: ptr(400), MinGrandmasterLP: ptr(0)},
+			wantChallenger:   400,
+			wantGrandmaster:  150,
+			wantChallengerAt: ptr(2),
+			wantGMAt:         ptr(6),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateCutoffs(league("p", tt.ladder...).Entries, tt.cfg)
+			if got.Challenger != tt.wantChallenger || got.Grandmaster != tt.wantGrandmaster {
+				t.Errorf("cutoffs = %d/%d, want %d/%d", got.Challenger, got.Grandmaster, tt.wantChallenger, tt.wantGrandmaster)
+			}
+			if got.Ladder.Size != len(tt.ladder) {
+				t.Errorf("ladder size = %d, want %d", got.Ladder.Size, len(tt.ladder))
+			}
+			checkIndex(t, "Challenger", got.Ladder.ChallengerIndex, tt.wantChallengerAt)
+			checkIndex(t, "Grandmaster", got.Ladder.GrandmasterIndex, tt.wantGMAt)
+		})
+	}
+}
+
+func checkIndex(t *testing.T, tier string, got, want *int) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s index = %v, want %v", tier, got, want)
+	case *got != *want:
+		t.Errorf("%s index = %d, want %d", tier, *got, *want)
+	}
+}
+
+func TestCalculateCutoffsSemantics(t *testing.T) {
+	// Every player sits below the default floors.
+	ladder := league("p", 450, 300, 250, 150, 100).Entries
+	tests := []struct {
+		name                            string
+		cfg                             QueueConfig
+		wantChallenger, wantGrandmaster int
+	}{
+		{"floor by default", QueueConfig{Challenger: 2, Grandmaster: 2}, DefaultMinChallengerLP, DefaultMinGrandmasterLP},
+		{"floor", QueueConfig{Challenger: 2, Grandmaster: 2, Cutoff: CutoffFloor}, DefaultMinChallengerLP, DefaultMinGrandmasterLP},
+		{"rank", QueueConfig{Challenger: 2, Grandmaster: 2, Cutoff: CutoffRank}, 300, 150},
+		{"rank with a ladder too short", QueueConfig{Challenger: 4, Grandmaster: 4, Cutoff: CutoffRank}, 150, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateCutoffs(ladder, tt.cfg)
+			if got.Challenger != tt.wantChallenger || got.Grandmaster != tt.wantGrandmaster {
+				t.Errorf("cutoffs = %d/%d, want %d/%d", got.Challenger, got.Grandmaster, tt.wantChallenger, tt.wantGrandmaster)
+			}
+		})
+	}
+}
+
+func TestCalculateCutoffsInterpolation(t *testing.T) {
+	// Ten players 100 LP apart, 1000 down to 100: 25% lands on rank 2.5 and
+	// a further 30% on rank 5.5.
+	evenly := league("p", descending(1000, 100, 10)...).Entries
+	// A steep top: the interpolated LP stays between its two ranks.
+	steep := league("p", 2000, 1200, 1100, 1050, 1000, 990, 980, 970, 960, 950).Entries
+	percentiles := QueueConfig{Challenger: 3, Grandmaster: 3, ChallengerPercentile: 25, GrandmasterPercentile: 30}
+	interpolated := percentiles
+	interpolated.Interpolate = true
+
+	tests := []struct {
+		name                            string
+		ladder                          []LeagueEntry
+		cfg                             QueueConfig
+		wantChallenger, wantGrandmaster int
+		wantIndexes                     [2]int
+	}{
+		{"truncated", evenly, percentiles, 900, 600, [2]int{1, 4}},
+		{"interpolated", evenly, interpolated, 850, 550, [2]int{1, 4}},
+		{"steep truncated", steep, percentiles, 1200, 1000, [2]int{1, 4}},
+		{"steep interpolated", steep, interpolated, 1150, 995, [2]int{1, 4}},
+		{"interpolated clamped to the floors", evenly, withFloors(interpolated, 870, 580), 870, 580, [2]int{1, 4}},
+		{"truncated above the floors", evenly, withFloors(percentiles, 870, 580), 900, 600, [2]int{1, 4}},
+		{"whole ranks are the same either way", evenly, QueueConfig{Challenger: 3, Grandmaster: 3, Interpolate: true}, 800, 500, [2]int{2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateCutoffs(tt.ladder, tt.cfg)
+			if got.Challenger != tt.wantChallenger || got.Grandmaster != tt.wantGrandmaster {
+				t.Errorf("cutoffs = %d/%d, want %d/%d", got.Challenger, got.Grandmaster, tt.wantChallenger, tt.wantGrandmaster)
+			}
+			checkIndex(t, "Challenger", got.Ladder.ChallengerIndex, &tt.wantIndexes[0])
+			checkIndex(t, "Grandmaster", got.Ladder.GrandmasterIndex, &tt.wantIndexes[1])
+		})
+	}
+}
+
+// withFloors returns q with its minimum LP overridden.
+func withFloors(q QueueConfig, challenger, grandmaster int) QueueConfig {
+	q.MinChallengerLP, q.MinGrandmasterLP = ptr(challenger), ptr(grandmaster)
+	return q
+}
@@ -0,0 +1,63 @@
+package cutoff_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// stubFetcher serves the same small apex leagues for every region and queue.
+type stubFetcher struct{}
+
+func (stubFetcher) Fetch(ctx context.Context, region, league, queueType string) (cutoff.LeagueResponse, error) {
+	lps := map[string][]int{
+		cutoff.LeagueChallenger:  {1200, 1100, 1000},
+		cutoff.LeagueGrandmaster: {900, 800, 700},
+		cutoff.LeagueMaster:      {600, 500, 400},
+	}[league]
+	var resp cutoff.LeagueResponse
+	for i, lp := range lps {
+		resp.Entries = append(resp.Entries, cutoff.LeagueEntry{PUUID: fmt.Sprintf("%s-%d", league, i), LeaguePoints: lp})
+	}
+	return resp, nil
+}
+
+func ExampleComputeRegion() {
+	queues := cutoff.Queues{
+		SoloDuo: cutoff.QueueConfig{Challenger: 2, Grandmaster: 3},
+		Flex:    cutoff.QueueConfig{Challenger: 1, Grandmaster: 1},
+	}
+	data, err := cutoff.ComputeRegion(context.Background(), stubFetcher{}, "euw1", queues, cutoff.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("solo/duo: challenger %d LP, grandmaster %d LP\n", data.RANKED_SOLO_5x5.Challenger, data.RANKED_SOLO_5x5.Grandmaster)
+	fmt.Printf("flex: challenger %d LP, grandmaster %d LP\n", data.RANKED_FLEX_SR.Challenger, data.RANKED_FLEX_SR.Grandmaster)
+	// Output:
+	// solo/duo: challenger 1100 LP, grandmaster 800 LP
+	// flex: challenger 1200 LP, grandmaster 1100 LP
+}
+
+func ExampleCreateLadder() {
+	challenger := cutoff.LeagueResponse{Entries: []cutoff.LeagueEntry{{PUUID: "a", LeaguePoints: 1100}, {PUUID: "b", LeaguePoints: 1300}}}
+	grandmaster := cutoff.LeagueResponse{Entries: []cutoff.LeagueEntry{{PUUID: "c", LeaguePoints: 900}}}
+	// b shows up again in Master, as when a player moves between the
+	// league requests; the ladder keeps them once.
+	master := cutoff.LeagueResponse{Entries: []cutoff.LeagueEntry{{PUUID: "d", LeaguePoints: 600}, {PUUID: "b", LeaguePoints: 1300}}}
+
+	ladder := cutoff.CreateLadder(challenger, grandmaster, master)
+	for rank, entry := range ladder {
+		fmt.Printf("%d. %s %d LP\n", rank+1, entry.PUUID, entry.LeaguePoints)
+	}
+
+	cutoffs := cutoff.CalculateCutoffs(ladder, cutoff.QueueConfig{Challenger: 1, Grandmaster: 2})
+	fmt.Printf("challenger %d LP, grandmaster %d LP\n", cutoffs.Challenger, cutoffs.Grandmaster)
+	// Output:
+	// 1. b 1300 LP
+	// 2. a 1100 LP
+	// 3. c 900 LP
+	// 4. d 600 LP
+	// challenger 1300 LP, grandmaster 900 LP
+}
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// DefaultArchiveLayout archives the cutoffs of every day in their own
+// directory.
+const DefaultArchiveLayout = "2006-01-02"
+
+// ArchiveLayout decides where the archived copies of the cutoffs go. Layout is
+// a Go time layout interpreted relative to the output directory, so the
+// default "2006-01-02" yields cdn/2024-01-31/cutoffs.json and "2006/01" a
+// monthly cdn/2024/01/cutoffs.json. Dates roll over at midnight in Location.
+type ArchiveLayout struct {
+	Layout   string
+	Location *time.Location
+}
+
+// NewArchiveLayout validates layout and resolves the time zone tz, where an
+// empty tz means UTC.
+func NewArchiveLayout(layout, tz string) (ArchiveLayout, error) {
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return ArchiveLayout{}, fmt.Errorf("load time zone %q: %w", tz, err)
+		}
+	}
+
+	// Every period needs its own directory, so the layout must at least
+	// include the year, and it must stay inside the output directory
+	// without clobbering current/.
+	if !strings.Contains(layout, "2006") {
+		return ArchiveLayout{}, fmt.Errorf("archive layout %q must contain the year (2006)", layout)
+	}
+	sample := time.Date(2024, time.December, 31, 0, 0, 0, 0, loc).Format(layout)
+	clean := path.Clean(sample)
+	if clean != sample || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return ArchiveLayout{}, fmt.Errorf("archive layout %q must produce a clean relative path, got %q", layout, sample)
+	}
+	if clean == "current" || strings.HasPrefix(clean, "current/") {
+		return ArchiveLayout{}, fmt.Errorf("archive layout %q collides with the current directory", layout)
+	}
+	if _, err := time.ParseInLocation(layout, sample, loc); err != nil {
+		return ArchiveLayout{}, fmt.Errorf("archive layout %q cannot be parsed back: %w", layout, err)
+	}
+	return ArchiveLayout{Layout: layout, Location: loc}, nil
+}
+
+// Dir returns the archive directory, relative to the output directory, that
+// holds the cutoffs of t.
+func (l ArchiveLayout) Dir(t time.Time) string {
+	return filepath.FromSlash(t.In(l.Location).Format(l.Layout))
+}
+
+// Path returns the path of the archived cutoffs file for t.
+func (l ArchiveLayout) Path(outputDir string, t time.Time) string {
+	return filepath.Join(outputDir, l.Dir(t), "cutoffs.json")
+}
+
+// PeriodStart returns the start of the archive period t falls in.
+func (l ArchiveLayout) PeriodStart(t time.Time) time.Time {
+	// NewArchiveLayout checked that the layout parses back.
+	start, _ := time.ParseInLocation(l.Layout, t.In(l.Location).Format(l.Layout), l.Location)
+	return start
+}
+
+// PeriodEnd returns the first day after start that is archived in a different
+// directory than start.
+func (l ArchiveLayout) PeriodEnd(start time.Time) time.Time {
+	name := l.Dir(start)
+	end := start.AddDate(0, 0, 1)
+	for i := 0; i < 400 && l.Dir(end) == name; i++ {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// PruneArchives removes the archive directories in baseDir whose whole period
+// ended more than retentionDays days before now. Only directories whose path
+// parses with the archive layout are considered, so current/ and anything
+// else an operator put there is never touched.
+func PruneArchives(baseDir string, layout ArchiveLayout, retentionDays int, now time.Time) error {
+	year, month, day := now.In(layout.Location).Date()
+	cutoff := time.Date(year, month, day, 0, 0, 0, 0, layout.Location).AddDate(0, 0, -retentionDays)
+	maxDepth := strings.Count(layout.Layout, "/") + 1
+
+	var expired []string
+	err := filepath.WalkDir(baseDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || p == baseDir {
+			return nil
+		}
+		rel, err := filepath.Rel(baseDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "current" {
+			return fs.SkipDir
+		}
+
+		start, err := time.ParseInLocation(layout.Layout, rel, layout.Location)
+		if err != nil || start.Format(layout.Layout) != rel {
+			if strings.Count(rel, "/")+1 >= maxDepth {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !layout.PeriodEnd(start).After(cutoff) {
+			expired = append(expired, p)
+		}
+		return fs.SkipDir
+	})
+	if err != nil {
+		return fmt.Errorf("scan directory %s: %w", baseDir, err)
+	}
+
+	for _, dirPath := range expired {
+		if err := os.RemoveAll(dirPath); err != nil {
+			return fmt.Errorf("remove directory %s: %w", dirPath, err)
+		}
+		slog.Info("Removed expired archive", "path", dirPath)
+		// Drop parents a nested layout left empty; os.Remove refuses
+		// non-empty directories.
+		for parent := filepath.Dir(dirPath); parent != baseDir && parent != "."; parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
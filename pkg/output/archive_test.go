@@ -0,0 +1,65 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneArchives(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		layout string
+		dirs   []string
+		kept   []string
+		pruned []string
+	}{
+		{
+			name:   "daily",
+			layout: DefaultArchiveLayout,
+			dirs:   []string{"2024-03-20", "2024-03-21", "2024-03-22", "2024-03-31", "current", "notes"},
+			kept:   []string{"2024-03-22", "2024-03-31", "current", "notes"},
+			pruned: []string{"2024-03-20", "2024-03-21"},
+		},
+		{
+			name:   "monthly keeps a period that overlaps the window",
+			layout: "2006/01",
+			dirs:   []string{"2024/01", "2024/02", "2024/03", "current"},
+			kept:   []string{"2024/03", "current"},
+			pruned: []string{"2024/01", "2024/02"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			for _, dir := range tt.dirs {
+				if err := os.MkdirAll(filepath.Join(base, dir), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(base, dir, "cutoffs.json"), []byte("{}"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			layout, err := NewArchiveLayout(tt.layout, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := PruneArchives(base, layout, 9, now); err != nil {
+				t.Fatalf("PruneArchives: %v", err)
+			}
+			for _, dir := range tt.kept {
+				if _, err := os.Stat(filepath.Join(base, dir)); err != nil {
+					t.Errorf("%s was removed, want it kept", dir)
+				}
+			}
+			for _, dir := range tt.pruned {
+				if _, err := os.Stat(filepath.Join(base, dir)); !os.IsNotExist(err) {
+					t.Errorf("%s still exists, want it removed", dir)
+				}
+			}
+		})
+	}
+}
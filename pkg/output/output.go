@@ -0,0 +1,423 @@
+// Package output writes the computed cutoffs to the output directory: the
+// current files, the dated archive and the per-region files.
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// Options controls which files Write produces.
+type Options struct {
+	Dir     string
+	Archive ArchiveLayout
+	// Gzip additionally writes a gzip-compressed copy of the current file,
+	// compressed at GzipLevel.
+	Gzip      bool
+	GzipLevel int
+	// CSV additionally writes the current cutoffs as CSV.
+	CSV bool
+	// Flat additionally writes the current cutoffs as a single flat JSON
+	// object, for clients of the legacy format.
+	Flat bool
+	// Minify writes compact JSON instead of indenting it.
+	Minify bool
+	// DedupeArchive hard-links the dated file to the previous period's when
+	// the cutoffs are unchanged apart from their timestamps, so unchanging
+	// periods share one file. The linked file keeps the timestamps of the
+	// period the cutoffs were first archived in.
+	DedupeArchive bool
+}
+
+// Marshal encodes v as JSON, indented unless opts.Minify is set.
+func (opts Options) Marshal(v any) ([]byte, error) {
+	if opts.Minify {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "    ")
+}
+
+// SchemaVersion is the version of the shape of the cutoffs.json files, written
+// to their SchemaVersionKey. Bump it whenever the shape changes incompatibly
+// and record the change here.
+//
+//	1: region names mapped to their RegionData, i.e. the cutoffs nested by
+//	   queue and tier; the per-region files hold the region's RegionData.
+const SchemaVersion = 1
+
+// SchemaVersionKey is the key holding the SchemaVersion in every cutoffs.json
+// file. It is reserved: no region is ever named after it, and consumers
+// iterating the regions of the combined file must skip it, since its value
+// is a number rather than a region's cutoffs.
+const SchemaVersionKey = "schemaVersion"
+
+// File is the combined cutoffs.json document: the regions' cutoffs keyed by
+// region, next to a leading SchemaVersionKey.
+type File struct {
+	SchemaVersion int
+	Regions       map[string]cutoff.RegionData
+}
+
+func (f File) MarshalJSON() ([]byte, error) {
+	regions, err := json.Marshal(f.Regions)
+	if err != nil {
+		return nil, err
+	}
+	return withSchemaVersion(f.SchemaVersion, regions), nil
+}
+
+// UnmarshalJSON also reads archives written before the SchemaVersionKey,
+// which count as version 1.
+func (f *File) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := takeSchemaVersion(raw, &f.SchemaVersion); err != nil {
+		return err
+	}
+	f.Regions = make(map[string]cutoff.RegionData, len(raw))
+	for region, value := range raw {
+		var data cutoff.RegionData
+		if err := json.Unmarshal(value, &data); err != nil {
+			return fmt.Errorf("decode region %s: %w", region, err)
+		}
+		f.Regions[region] = data
+	}
+	return nil
+}
+
+// RegionFile is a per-region cutoffs.json document: the region's cutoffs
+// with a leading SchemaVersionKey.
+type RegionFile struct {
+	SchemaVersion int
+	Data          cutoff.RegionData
+}
+
+func (f RegionFile) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	return withSchemaVersion(f.SchemaVersion, data), nil
+}
+
+// UnmarshalJSON also reads files written before the SchemaVersionKey, which
+// count as version 1.
+func (f *RegionFile) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := takeSchemaVersion(raw, &f.SchemaVersion); err != nil {
+		return err
+	}
+	f.Data = cutoff.RegionData{}
+	return json.Unmarshal(data, &f.Data)
+}
+
+// withSchemaVersion returns the JSON object with a leading SchemaVersionKey
+// holding version; null counts as an empty object.
+func withSchemaVersion(version int, object []byte) []byte {
+	versioned := fmt.Appendf(nil, `{%q:%d`, SchemaVersionKey, version)
+	if len(object) <= len("{}") || object[0] != '{' {
+		return append(versioned, '}')
+	}
+	return append(append(versioned, ','), object[1:]...)
+}
+
+// takeSchemaVersion decodes the SchemaVersionKey of raw into version, or 1
+// when it is missing, and removes it from raw.
+func takeSchemaVersion(raw map[string]json.RawMessage, version *int) error {
+	*version = 1
+	value, ok := raw[SchemaVersionKey]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(value, version); err != nil {
+		return fmt.Errorf("decode %s: %w", SchemaVersionKey, err)
+	}
+	delete(raw, SchemaVersionKey)
+	return nil
+}
+
+// Write writes outputData to the current files and the dated archive of
+// opts.Dir, plus whichever extra formats opts enables.
+func Write(opts Options, outputData map[string]cutoff.RegionData) error {
+	outputDir := opts.Dir
+	jsonData, err := opts.Marshal(File{SchemaVersion: SchemaVersion, Regions: outputData})
+	if err != nil {
+		return fmt.Errorf("marshal JSON: %w", err)
+	}
+
+	if err := EnsureDir(outputDir); err != nil {
+		return err
+	}
+
+	currentDir := filepath.Join(outputDir, "current")
+	if err := EnsureDir(currentDir); err != nil {
+		return err
+	}
+	now := time.Now()
+	datedPath := opts.Archive.Path(outputDir, now)
+	if err := EnsureDir(filepath.Dir(datedPath)); err != nil {
+		return err
+	}
+
+	// The current and dated files are staged together and only moved into
+	// place once both are fully written, so a failed write leaves both as
+	// they were. The dated file goes first so current never runs ahead of
+	// the archive.
+	var tx fileTx
+	previousPath := opts.Archive.Path(outputDir, opts.Archive.PeriodStart(now).Add(-time.Nanosecond))
+	if opts.DedupeArchive && unchangedSince(previousPath, outputData) {
+		if err := tx.stageLink(previousPath, datedPath); err != nil {
+			tx.rollback()
+			return err
+		}
+	} else if err := tx.stage(datedPath, jsonData); err != nil {
+		tx.rollback()
+		return err
+	}
+	if err := tx.stage(filepath.Join(currentDir, "cutoffs.json"), jsonData); err != nil {
+		tx.rollback()
+		return err
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+
+	if opts.Gzip {
+		if err := writeGzipFile(filepath.Join(currentDir, "cutoffs.json.gz"), jsonData, opts.GzipLevel); err != nil {
+			return err
+		}
+	}
+	if opts.CSV {
+		csvData, err := cutoffsCSV(outputData)
+		if err != nil {
+			return err
+		}
+		if err := WriteFile(filepath.Join(currentDir, "cutoffs.csv"), csvData); err != nil {
+			return err
+		}
+	}
+	if opts.Flat {
+		flatData, err := opts.Marshal(flattenCutoffs(outputData))
+		if err != nil {
+			return fmt.Errorf("marshal flat JSON: %w", err)
+		}
+		if err := WriteFile(filepath.Join(currentDir, "cutoffs-flat.json"), flatData); err != nil {
+			return err
+		}
+	}
+	return writeRegionFiles(opts, currentDir, outputData)
+}
+
+// unchangedSince reports whether the cutoffs archived at path equal
+// outputData apart from their timestamps. An archive that can't be read
+// counts as changed.
+func unchangedSince(path string, outputData map[string]cutoff.RegionData) bool {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var archived File
+	if err := json.Unmarshal(raw, &archived); err != nil {
+		return false
+	}
+	previous, err := json.Marshal(withoutTimestamps(archived.Regions))
+	if err != nil {
+		return false
+	}
+	current, err := json.Marshal(withoutTimestamps(outputData))
+	return err == nil && bytes.Equal(previous, current)
+}
+
+// withoutTimestamps returns a copy of outputData with every UpdatedAt
+// cleared.
+func withoutTimestamps(outputData map[string]cutoff.RegionData) map[string]cutoff.RegionData {
+	stripped := make(map[string]cutoff.RegionData, len(outputData))
+	for region, data := range outputData {
+		data.UpdatedAt = time.Time{}
+		data.RANKED_SOLO_5x5.UpdatedAt = time.Time{}
+		data.RANKED_FLEX_SR.UpdatedAt = time.Time{}
+		stripped[region] = data
+	}
+	return stripped
+}
+
+// writeRegionFiles writes each region's cutoffs to <dir>/<region>/cutoffs.json
+// for consumers that only need a single region.
+func writeRegionFiles(opts Options, dir string, outputData map[string]cutoff.RegionData) error {
+	for region, data := range outputData {
+		jsonData, err := opts.Marshal(RegionFile{SchemaVersion: SchemaVersion, Data: data})
+		if err != nil {
+			return fmt.Errorf("marshal JSON for region %s: %w", region, err)
+		}
+
+		regionDir := filepath.Join(dir, region)
+		if err := EnsureDir(regionDir); err != nil {
+			return err
+		}
+		if err := WriteFile(filepath.Join(regionDir, "cutoffs.json"), jsonData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cutoffsCSV renders the cutoffs as CSV with one row per region, queue and
+// tier, sorted so consecutive files diff cleanly.
+func cutoffsCSV(outputData map[string]cutoff.RegionData) ([]byte, error) {
+	regions := make([]string, 0, len(outputData))
+	for region := range outputData {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"region", "queue", "tier", "cutoff_lp"})
+	for _, region := range regions {
+		for _, value := range outputData[region].Values() {
+			w.Write([]string{region, value.Queue, value.Tier, strconv.Itoa(value.LP)})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("write CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// flattenCutoffs maps "<region>_<queue>_<tier>" to the cutoff LP of every
+// computed queue, e.g. "na1_RANKED_SOLO_5x5_challenger".
+func flattenCutoffs(outputData map[string]cutoff.RegionData) map[string]int {
+	flat := make(map[string]int)
+	for region, data := range outputData {
+		for _, value := range data.Values() {
+			flat[region+"_"+value.Queue+"_"+value.Tier] = value.LP
+		}
+	}
+	return flat
+}
+
+// writeGzipFile atomically writes the gzip-compressed data to filePath.
+func writeGzipFile(filePath string, data []byte, level int) error {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return fmt.Errorf("create gzip writer for %s: %w", filePath, err)
+	}
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("compress %s: %w", filePath, err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress %s: %w", filePath, err)
+	}
+	return WriteFile(filePath, buf.Bytes())
+}
+
+// EnsureDir creates dirPath and its parents unless it already exists.
+func EnsureDir(dirPath string) error {
+	if _, err := os.Stat(dirPath); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(dirPath, 0755); err != nil {
+			return fmt.Errorf("create directory %s: %w", dirPath, err)
+		}
+	}
+	return nil
+}
+
+// WriteFile atomically replaces filePath with data by writing to a temporary
+// file in the same directory and renaming it into place, so readers never see
+// a partially written file.
+func WriteFile(filePath string, data []byte) error {
+	var tx fileTx
+	if err := tx.stage(filePath, data); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+// fileTx writes a group of files through temp files that are only renamed
+// into place on commit.
+type fileTx struct {
+	staged []stagedFile
+}
+
+type stagedFile struct {
+	tmp, path string
+}
+
+// stage writes data to a temp file next to filePath.
+func (tx *fileTx) stage(filePath string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file for %s: %w", filePath, err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write file to %s: %w", filePath, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("chmod file %s: %w", filePath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write file to %s: %w", filePath, err)
+	}
+	tx.staged = append(tx.staged, stagedFile{tmp: tmp.Name(), path: filePath})
+	return nil
+}
+
+// stageLink hard-links src to a temp file next to filePath.
+func (tx *fileTx) stageLink(src, filePath string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file for %s: %w", filePath, err)
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	if err := os.Link(src, tmp.Name()); err != nil {
+		return fmt.Errorf("link %s to %s: %w", filePath, src, err)
+	}
+	tx.staged = append(tx.staged, stagedFile{tmp: tmp.Name(), path: filePath})
+	return nil
+}
+
+// commit renames the staged files into place in the order they were staged.
+// Files not renamed because of an error are discarded.
+func (tx *fileTx) commit() error {
+	defer tx.rollback()
+	for len(tx.staged) > 0 {
+		f := tx.staged[0]
+		if err := os.Rename(f.tmp, f.path); err != nil {
+			return fmt.Errorf("rename file to %s: %w", f.path, err)
+		}
+		tx.staged = tx.staged[1:]
+	}
+	return nil
+}
+
+// rollback discards the staged files that were not committed.
+func (tx *fileTx) rollback() {
+	for _, f := range tx.staged {
+		os.Remove(f.tmp)
+	}
+	tx.staged = nil
+}
@@ -0,0 +1,346 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// testOptions returns options writing to a temp dir with the default archive
+// layout.
+func testOptions(t *testing.T) Options {
+	t.Helper()
+	layout, err := NewArchiveLayout(DefaultArchiveLayout, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Options{Dir: t.TempDir(), Archive: layout}
+}
+
+// testRegions returns computed cutoffs for two regions.
+func testRegions() map[string]cutoff.RegionData {
+	at := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+	data := func(challenger, grandmaster int) cutoff.RegionData {
+		c := cutoff.Cutoffs{Challenger: challenger, Grandmaster: grandmaster, UpdatedAt: at}
+		return cutoff.RegionData{RANKED_SOLO_5x5: c, RANKED_FLEX_SR: c, UpdatedAt: at}
+	}
+	return map[string]cutoff.RegionData{"euw1": data(900, 400), "kr": data(1100, 600)}
+}
+
+// readJSON decodes the JSON file at path into v.
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatalf("decode %s: %v", path, err)
+	}
+}
+
+func TestWriteRegionFiles(t *testing.T) {
+	opts := testOptions(t)
+	if err := Write(opts, testRegions()); err != nil {
+		t.Fatal(err)
+	}
+
+	var combined File
+	readJSON(t, filepath.Join(opts.Dir, "current", "cutoffs.json"), &combined)
+	if combined.SchemaVersion != SchemaVersion {
+		t.Errorf("schemaVersion = %d, want %d", combined.SchemaVersion, SchemaVersion)
+	}
+	if !reflect.DeepEqual(combined.Regions, testRegions()) {
+		t.Errorf("combined file = %+v, want %+v", combined.Regions, testRegions())
+	}
+	for region, want := range testRegions() {
+		var file RegionFile
+		readJSON(t, filepath.Join(opts.Dir, "current", region, "cutoffs.json"), &file)
+		if file.SchemaVersion != SchemaVersion {
+			t.Errorf("%s schemaVersion = %d, want %d", region, file.SchemaVersion, SchemaVersion)
+		}
+		if !reflect.DeepEqual(file.Data, want) {
+			t.Errorf("%s file = %+v, want the combined file's %+v", region, file.Data, want)
+		}
+	}
+}
+
+func TestWriteMinify(t *testing.T) {
+	files := []string{filepath.Join("current", "cutoffs.json"), filepath.Join("current", "euw1", "cutoffs.json")}
+	written := make(map[bool][][]byte)
+	for _, minify := range []bool{false, true} {
+		opts := testOptions(t)
+		opts.Minify = minify
+		if err := Write(opts, testRegions()); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range append(files, opts.Archive.Path("", time.Now())) {
+			raw, err := os.ReadFile(filepath.Join(opts.Dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			written[minify] = append(written[minify], raw)
+		}
+	}
+
+	for i, indented := range written[false] {
+		minified := written[true][i]
+		if len(minified) >= len(indented) {
+			t.Errorf("file %d: minified size %d, want less than the indented %d", i, len(minified), len(indented))
+		}
+		if !bytes.Contains(indented, []byte("\n    ")) || bytes.Contains(minified, []byte("\n")) {
+			t.Errorf("file %d isn't indented or minified as asked", i)
+		}
+		var a, b any
+		if err := json.Unmarshal(indented, &a); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(minified, &b); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("file %d: minified data %v, want the indented %v", i, b, a)
+		}
+	}
+}
+
+func TestWriteFlat(t *testing.T) {
+	regions := testRegions()
+	kr := regions["kr"]
+	kr.RANKED_FLEX_SR = cutoff.Cutoffs{}
+	kr.RANKED_SOLO_5x5.ChallengerOnly = true
+	regions["kr"] = kr
+
+	opts := testOptions(t)
+	opts.Flat = true
+	if err := Write(opts, regions); err != nil {
+		t.Fatal(err)
+	}
+	var flat map[string]int
+	readJSON(t, filepath.Join(opts.Dir, "current", "cutoffs-flat.json"), &flat)
+	want := map[string]int{
+		"euw1_RANKED_SOLO_5x5_challenger":  900,
+		"euw1_RANKED_SOLO_5x5_grandmaster": 400,
+		"euw1_RANKED_FLEX_SR_challenger":   900,
+		"euw1_RANKED_FLEX_SR_grandmaster":  400,
+		"kr_RANKED_SOLO_5x5_challenger":    1100,
+	}
+	if !reflect.DeepEqual(flat, want) {
+		t.Errorf("flat cutoffs = %v, want %v", flat, want)
+	}
+}
+
+func TestWriteKeepsCurrentWhenArchiveFails(t *testing.T) {
+	opts := testOptions(t)
+	if err := Write(opts, testRegions()); err != nil {
+		t.Fatal(err)
+	}
+	currentPath := filepath.Join(opts.Dir, "current", "cutoffs.json")
+	before, err := os.ReadFile(currentPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in place of the dated file makes its rename fail.
+	datedPath := opts.Archive.Path(opts.Dir, time.Now())
+	if err := os.Remove(datedPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(datedPath, "blocker"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	changed := testRegions()
+	changed["euw1"] = changed["kr"]
+	if err := Write(opts, changed); err == nil {
+		t.Fatal("Write succeeded although the dated file couldn't be written")
+	}
+
+	after, err := os.ReadFile(currentPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, before) {
+		t.Error("current file changed although the dated write failed")
+	}
+	assertNoTempFiles(t, filepath.Dir(datedPath), filepath.Dir(currentPath))
+}
+
+func TestFileTxRollsBackOnFailedStage(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	if err := os.WriteFile(first, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var tx fileTx
+	if err := tx.stage(first, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.stage(filepath.Join(dir, "missing", "second.json"), []byte("new")); err == nil {
+		t.Fatal("staging into a missing directory succeeded")
+	}
+	tx.rollback()
+
+	if got, err := os.ReadFile(first); err != nil || string(got) != "old" {
+		t.Errorf("first file = %q, %v, want it unchanged", got, err)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+// assertNoTempFiles fails when a staged temp file was left behind in dirs.
+func assertNoTempFiles(t *testing.T, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		temps, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(temps) > 0 {
+			t.Errorf("temp files left behind: %v", temps)
+		}
+	}
+}
+
+func TestFileJSON(t *testing.T) {
+	raw, err := json.Marshal(File{SchemaVersion: SchemaVersion, Regions: testRegions()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte(`{"schemaVersion":1,"euw1":`)) {
+		t.Errorf("file starts with %.40s, want schemaVersion first", raw)
+	}
+	if empty, _ := json.Marshal(File{SchemaVersion: SchemaVersion}); string(empty) != `{"schemaVersion":1}` {
+		t.Errorf("file without regions = %s", empty)
+	}
+
+	tests := []struct {
+		name, raw   string
+		wantVersion int
+	}{
+		{"current", string(raw), SchemaVersion},
+		{"archive without schemaVersion", `{"euw1":{"RANKED_SOLO_5x5":{"challenger":900}}}`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f File
+			if err := json.Unmarshal([]byte(tt.raw), &f); err != nil {
+				t.Fatal(err)
+			}
+			if f.SchemaVersion != tt.wantVersion {
+				t.Errorf("schemaVersion = %d, want %d", f.SchemaVersion, tt.wantVersion)
+			}
+			if _, ok := f.Regions["schemaVersion"]; ok || f.Regions["euw1"].RANKED_SOLO_5x5.Challenger != 900 {
+				t.Errorf("regions = %+v, want only euw1", f.Regions)
+			}
+		})
+	}
+}
+
+func TestRegionFileJSON(t *testing.T) {
+	data := testRegions()["euw1"]
+	raw, err := json.Marshal(RegionFile{SchemaVersion: SchemaVersion, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte(`{"schemaVersion":1,"RANKED_SOLO_5x5":`)) {
+		t.Errorf("file starts with %.40s, want schemaVersion first", raw)
+	}
+	// Clients decoding the region file as before still get the cutoffs.
+	var plain cutoff.RegionData
+	if err := json.Unmarshal(raw, &plain); err != nil || !reflect.DeepEqual(plain, data) {
+		t.Errorf("region file decoded as RegionData = %+v, %v, want %+v", plain, err, data)
+	}
+
+	tests := []struct {
+		name, raw   string
+		wantVersion int
+	}{
+		{"current", string(raw), SchemaVersion},
+		{"file without schemaVersion", `{"RANKED_SOLO_5x5":{"challenger":900}}`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f RegionFile
+			if err := json.Unmarshal([]byte(tt.raw), &f); err != nil {
+				t.Fatal(err)
+			}
+			if f.SchemaVersion != tt.wantVersion {
+				t.Errorf("schemaVersion = %d, want %d", f.SchemaVersion, tt.wantVersion)
+			}
+			if f.Data.RANKED_SOLO_5x5.Challenger != 900 {
+				t.Errorf("solo/duo Challenger cutoff = %d, want 900", f.Data.RANKED_SOLO_5x5.Challenger)
+			}
+		})
+	}
+}
+
+func TestWriteDedupeArchive(t *testing.T) {
+	// The previous period holds the same cutoffs, archived a day earlier.
+	earlier := testRegions()
+	for region, data := range earlier {
+		data.UpdatedAt = data.UpdatedAt.AddDate(0, 0, -1)
+		data.RANKED_SOLO_5x5.UpdatedAt = data.UpdatedAt
+		data.RANKED_FLEX_SR.UpdatedAt = data.UpdatedAt
+		earlier[region] = data
+	}
+	changed := testRegions()
+	kr := changed["kr"]
+	kr.RANKED_SOLO_5x5.Challenger++
+	changed["kr"] = kr
+
+	tests := []struct {
+		name   string
+		dedupe bool
+		data   map[string]cutoff.RegionData
+		linked bool
+	}{
+		{"unchanged cutoffs are linked", true, testRegions(), true},
+		{"changed cutoffs are written", true, changed, false},
+		{"disabled", false, testRegions(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t)
+			opts.DedupeArchive = tt.dedupe
+			now := time.Now()
+			previousPath := opts.Archive.Path(opts.Dir, opts.Archive.PeriodStart(now).Add(-time.Nanosecond))
+			if err := os.MkdirAll(filepath.Dir(previousPath), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			previous, err := opts.Marshal(File{SchemaVersion: SchemaVersion, Regions: earlier})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(previousPath, previous, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Write(opts, tt.data); err != nil {
+				t.Fatal(err)
+			}
+			previousInfo, err := os.Stat(previousPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			datedInfo, err := os.Stat(opts.Archive.Path(opts.Dir, now))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if linked := os.SameFile(previousInfo, datedInfo); linked != tt.linked {
+				t.Errorf("dated file linked to the previous period = %t, want %t", linked, tt.linked)
+			}
+			var current File
+			readJSON(t, filepath.Join(opts.Dir, "current", "cutoffs.json"), &current)
+			if !reflect.DeepEqual(current.Regions, tt.data) {
+				t.Errorf("current file = %+v, want %+v", current.Regions, tt.data)
+			}
+			assertNoTempFiles(t, opts.Dir)
+		})
+	}
+}
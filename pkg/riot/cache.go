@@ -0,0 +1,78 @@
+package riot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+const (
+	responseCacheMaxAge     = 15 * time.Minute
+	responseCacheMaxEntries = 512
+)
+
+// responseCache remembers the last league response per endpoint together with
+// its validators so unchanged leagues can be revalidated with a conditional
+// request instead of being downloaded again.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	response     cutoff.LeagueResponse
+	storedAt     time.Time
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]cachedResponse)}
+}
+
+// get returns the cached response for key unless it is missing or older than
+// responseCacheMaxAge.
+func (c *responseCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	if time.Since(entry.storedAt) > responseCacheMaxAge {
+		delete(c.entries, key)
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+// put stores entry under key, evicting the oldest entry when the cache is full.
+func (c *responseCache) put(key string, entry cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= responseCacheMaxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range c.entries {
+			if oldestKey == "" || e.storedAt.Before(oldest) {
+				oldestKey, oldest = k, e.storedAt
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+	c.entries[key] = entry
+}
+
+// refresh marks the entry under key as revalidated now.
+func (c *responseCache) refresh(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		entry.storedAt = time.Now()
+		c.entries[key] = entry
+	}
+}
@@ -0,0 +1,224 @@
+package riot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// rateUsageTracker records the worst rate-limit usage observed in responses.
+type rateUsageTracker struct {
+	mu       sync.Mutex
+	usage    float64
+	observed bool
+}
+
+// observe records the usage reported by the X-App-Rate-Limit and
+// X-App-Rate-Limit-Count response headers, if present.
+func (t *rateUsageTracker) observe(header http.Header) {
+	usage, ok := rateLimitUsage(header.Get("X-App-Rate-Limit"), header.Get("X-App-Rate-Limit-Count"))
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.observed || usage > t.usage {
+		t.usage = usage
+	}
+	t.observed = true
+}
+
+// TakeRateUsage returns the highest fraction of any rate-limit window used
+// since the previous call, and false when nothing was observed.
+func (t *rateUsageTracker) TakeRateUsage() (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage, observed := t.usage, t.observed
+	t.usage, t.observed = 0, false
+	return usage, observed
+}
+
+// rateLimitUsage parses Riot's rate-limit headers, formatted as comma-separated
+// "requests:seconds" pairs (e.g. "20:1,100:120" and "3:1,85:120"), and returns
+// the highest fraction used of any window.
+func rateLimitUsage(limits, counts string) (float64, bool) {
+	if limits == "" || counts == "" {
+		return 0, false
+	}
+
+	limitByWindow := make(map[string]int)
+	for _, pair := range strings.Split(limits, ",") {
+		limit, window, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(limit); err == nil && n > 0 {
+			limitByWindow[window] = n
+		}
+	}
+
+	var usage float64
+	found := false
+	for _, pair := range strings.Split(counts, ",") {
+		count, window, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		limit, known := limitByWindow[window]
+		if err != nil || !known {
+			continue
+		}
+		usage = max(usage, float64(n)/float64(limit))
+		found = true
+	}
+	return usage, found
+}
+
+// RegionLimiter paces requests with a token bucket per platform, since Riot
+// enforces its method rate limits per platform rather than globally. When
+// requests queue up, those for higher-priority leagues are sent first, so
+// under throttling the low-priority leagues are the ones still waiting when a
+// cycle's deadline cuts them off.
+type RegionLimiter struct {
+	rate       float64
+	burst      int
+	priorities map[string]int
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewRegionLimiter allows rate requests per second to every platform, with
+// bursts of up to burst requests. priorities ranks the leagues, higher first;
+// leagues missing from it come last.
+func NewRegionLimiter(rate float64, burst int, priorities map[string]int) *RegionLimiter {
+	return &RegionLimiter{rate: rate, burst: burst, priorities: priorities, buckets: make(map[string]*tokenBucket)}
+}
+
+// wait blocks until a request for league to region may be sent or ctx is
+// done.
+func (l *RegionLimiter) wait(ctx context.Context, region, league string) error {
+	l.mu.Lock()
+	bucket, ok := l.buckets[region]
+	if !ok {
+		bucket = &tokenBucket{rate: l.rate, burst: float64(l.burst), tokens: float64(l.burst), last: time.Now()}
+		l.buckets[region] = bucket
+	}
+	l.mu.Unlock()
+	return bucket.wait(ctx, l.priorities[league])
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	// waiters are the requests waiting for a token, highest priority first
+	// and in arrival order within a priority; timer fires when the next
+	// token is due.
+	waiters []*bucketWaiter
+	seq     uint64
+	timer   *time.Timer
+}
+
+type bucketWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// wait takes a token, sleeping until one is available. Tokens go to the
+// waiting request with the highest priority, the earliest among equals.
+func (b *tokenBucket) wait(ctx context.Context, priority int) error {
+	b.mu.Lock()
+	b.refill()
+	if len(b.waiters) == 0 && b.tokens >= 1 {
+		b.tokens--
+		b.mu.Unlock()
+		return nil
+	}
+	b.seq++
+	w := &bucketWaiter{priority: priority, seq: b.seq, ready: make(chan struct{})}
+	i := sort.Search(len(b.waiters), func(i int) bool { return b.waiters[i].priority < priority })
+	b.waiters = slices.Insert(b.waiters, i, w)
+	b.grant()
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if i := slices.Index(b.waiters, w); i >= 0 {
+			b.waiters = slices.Delete(b.waiters, i, i+1)
+		} else {
+			// The token was granted as ctx expired; hand it on.
+			b.tokens++
+		}
+		b.grant()
+		return ctx.Err()
+	}
+}
+
+// refill adds the tokens accrued since the last refill. b.mu must be held.
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// grant hands the available tokens to the waiters in order and schedules the
+// next grant while any are left waiting. b.mu must be held.
+func (b *tokenBucket) grant() {
+	for len(b.waiters) > 0 && b.tokens >= 1 {
+		b.tokens--
+		close(b.waiters[0].ready)
+		b.waiters = b.waiters[1:]
+	}
+	if len(b.waiters) == 0 || b.timer != nil {
+		return
+	}
+	delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	b.timer = time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.timer = nil
+		b.refill()
+		b.grant()
+	})
+}
+
+// ParseLeaguePriority turns a ranking of the apex tiers, highest priority
+// first, into the priority of each league for NewRegionLimiter.
+func ParseLeaguePriority(tiers []string) (map[string]int, error) {
+	leagues := map[string]string{
+		cutoff.TierChallenger:  cutoff.LeagueChallenger,
+		cutoff.TierGrandmaster: cutoff.LeagueGrandmaster,
+		cutoff.TierMaster:      cutoff.LeagueMaster,
+	}
+	priorities := make(map[string]int, len(tiers))
+	for i, tier := range tiers {
+		league, ok := leagues[strings.ToLower(tier)]
+		if !ok {
+			return nil, fmt.Errorf("unknown tier %q, want challenger, grandmaster or master", tier)
+		}
+		if _, dup := priorities[league]; dup {
+			return nil, fmt.Errorf("tier %q is listed more than once", tier)
+		}
+		priorities[league] = len(tiers) - i
+	}
+	return priorities, nil
+}
@@ -0,0 +1,116 @@
+package riot
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// grantOrder queues a request per league, in the given order, behind an
+// exhausted bucket of l and returns the order they were granted in.
+func grantOrder(t *testing.T, l *RegionLimiter, leagues []string) []string {
+	t.Helper()
+	ctx := context.Background()
+	// Take the only token so every following request has to queue.
+	if err := l.wait(ctx, "euw1", cutoff.LeagueMaster); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var granted []string
+	var wg sync.WaitGroup
+	for i, league := range leagues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.wait(ctx, "euw1", league); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			granted = append(granted, league)
+			mu.Unlock()
+		}()
+		// Wait until the request is queued, so the arrival order is the
+		// given one.
+		for queued := 0; queued <= i; {
+			time.Sleep(time.Millisecond)
+			bucket := l.buckets["euw1"]
+			bucket.mu.Lock()
+			queued = len(bucket.waiters)
+			bucket.mu.Unlock()
+		}
+	}
+	wg.Wait()
+	return granted
+}
+
+func TestRegionLimiterPriority(t *testing.T) {
+	arrival := []string{cutoff.LeagueMaster, cutoff.LeagueGrandmaster, cutoff.LeagueMaster, cutoff.LeagueChallenger}
+	tests := []struct {
+		name  string
+		tiers []string
+		want  []string
+	}{
+		{
+			"challenger first",
+			[]string{"challenger", "grandmaster", "master"},
+			[]string{cutoff.LeagueChallenger, cutoff.LeagueGrandmaster, cutoff.LeagueMaster, cutoff.LeagueMaster},
+		},
+		{
+			"master first",
+			[]string{"master", "challenger"},
+			[]string{cutoff.LeagueMaster, cutoff.LeagueMaster, cutoff.LeagueChallenger, cutoff.LeagueGrandmaster},
+		},
+		{"no priorities keeps the arrival order", nil, arrival},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priorities, err := ParseLeaguePriority(tt.tiers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := NewRegionLimiter(50, 1, priorities)
+			if got := grantOrder(t, l, arrival); !slices.Equal(got, tt.want) {
+				t.Errorf("granted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionLimiterCanceledWait(t *testing.T) {
+	l := NewRegionLimiter(1, 1, nil)
+	if err := l.wait(context.Background(), "euw1", cutoff.LeagueChallenger); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, "euw1", cutoff.LeagueChallenger); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want the deadline to cut the wait short", err)
+	}
+	// Other platforms have their own bucket.
+	if err := l.wait(context.Background(), "kr", cutoff.LeagueChallenger); err != nil {
+		t.Errorf("kr: %v, want its own token", err)
+	}
+}
+
+func TestParseLeaguePriority(t *testing.T) {
+	got, err := ParseLeaguePriority([]string{"Challenger", "grandmaster", "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{cutoff.LeagueChallenger: 3, cutoff.LeagueGrandmaster: 2, cutoff.LeagueMaster: 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("priorities = %v, want %v", got, want)
+	}
+	for _, tiers := range [][]string{{"iron"}, {"master", "master"}} {
+		if _, err := ParseLeaguePriority(tiers); err == nil {
+			t.Errorf("ParseLeaguePriority(%v) succeeded, want an error", tiers)
+		}
+	}
+}
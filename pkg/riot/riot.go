@@ -0,0 +1,288 @@
+// Package riot fetches the apex leagues from the Riot league-v4 API. Its
+// Fetcher implements cutoff.Fetcher.
+package riot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// DefaultBaseURL is the Riot API host, prefixed with the platform for each
+// request.
+const DefaultBaseURL = "api.riotgames.com"
+
+// maxBodySnippet is how much of an unexpected response body is quoted in
+// errors.
+const maxBodySnippet = 512
+
+// ErrUnauthorized is returned when Riot rejects the API key, which usually
+// means a development key expired.
+var ErrUnauthorized = errors.New("API key rejected")
+
+// ErrTransient marks fetch errors caused by a temporary problem on Riot's
+// side, such as an outage or rate limiting, that are worth retrying.
+var ErrTransient = errors.New("transient Riot API error")
+
+// Timeouts bound the phases of a Riot request up to its response
+// headers. Reading the body is only bounded by the request's context, so a
+// dead connection fails fast while a large league still has time to stream.
+type Timeouts struct {
+	Dial           time.Duration
+	TLSHandshake   time.Duration
+	ResponseHeader time.Duration
+}
+
+// NewClient returns the HTTP client for Riot requests. They go through
+// proxyURL when it is set, and otherwise through the proxy configured by
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY like every other request.
+func NewClient(proxyURL *url.URL, timeouts Timeouts) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	dialer := &net.Dialer{Timeout: timeouts.Dial, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = timeouts.TLSHandshake
+	transport.ResponseHeaderTimeout = timeouts.ResponseHeader
+	return &http.Client{Transport: transport}
+}
+
+// Fetcher is the cutoff.Fetcher backed by the Riot league-v4 API.
+type Fetcher struct {
+	apiKey    string
+	userAgent string
+	client    *http.Client
+	cache     *responseCache
+	// limiter paces the requests to each platform; nil means unlimited.
+	limiter *RegionLimiter
+	// paginated fetches the leagues page by page from league-exp-v4, which
+	// costs more requests but returns the full ladder of large regions.
+	paginated bool
+	rateUsageTracker
+
+	// baseURL is the default API base, see platformURL; regionBaseURLs
+	// overrides it per region and is replaced on config reloads.
+	baseURL        string
+	mu             sync.RWMutex
+	regionBaseURLs map[string]string
+}
+
+// NewFetcher returns a Fetcher sending requests with apiKey through client to
+// baseURL, see DefaultBaseURL. A nil limiter leaves the requests unpaced;
+// paginated fetches the leagues page by page from league-exp-v4.
+func NewFetcher(apiKey, userAgent, baseURL string, client *http.Client, limiter *RegionLimiter, paginated bool) *Fetcher {
+	return &Fetcher{
+		apiKey:    apiKey,
+		userAgent: userAgent,
+		baseURL:   baseURL,
+		client:    client,
+		cache:     newResponseCache(),
+		limiter:   limiter,
+		paginated: paginated,
+	}
+}
+
+// Fetch returns the league of queueType on region, revalidating a cached copy
+// when Riot sent validators with it.
+func (f *Fetcher) Fetch(ctx context.Context, region, league, queueType string) (cutoff.LeagueResponse, error) {
+	if f.paginated {
+		return f.fetchPages(ctx, region, league, queueType)
+	}
+	url := fmt.Sprintf("%s/lol/league/v4/%s/by-queue/%s", f.regionURL(region), league, queueType)
+	return f.get(ctx, region, league, url, region+"_"+queueType+"_"+league, func(body []byte) (cutoff.LeagueResponse, error) {
+		var leagueData cutoff.LeagueResponse
+		err := json.Unmarshal(body, &leagueData)
+		return leagueData, err
+	})
+}
+
+// SetRegionBaseURLs replaces the per-region base URL overrides.
+func (f *Fetcher) SetRegionBaseURLs(baseURLs map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regionBaseURLs = baseURLs
+}
+
+// regionURL returns the base URL of region's API.
+func (f *Fetcher) regionURL(region string) string {
+	f.mu.RLock()
+	base, ok := f.regionBaseURLs[region]
+	f.mu.RUnlock()
+	if !ok {
+		base = f.baseURL
+	}
+	return platformURL(base, region)
+}
+
+// platformURL resolves base for platform. A bare host such as
+// "api.riotgames.com" becomes "https://<platform>.api.riotgames.com"; a full
+// URL is used as is, with any "{platform}" replaced, so requests can be sent
+// to a gateway or a test server.
+func platformURL(base, platform string) string {
+	if !strings.Contains(base, "://") {
+		return "https://" + platform + "." + base
+	}
+	return strings.TrimSuffix(strings.ReplaceAll(base, "{platform}", platform), "/")
+}
+
+// ValidateBaseURL checks that base resolves to a usable HTTP(S) URL.
+func ValidateBaseURL(base string) error {
+	u, err := url.Parse(platformURL(base, "na1"))
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is neither a host nor an http(s) URL", base)
+	}
+	return nil
+}
+
+// maxLeaguePages bounds how many league-exp pages are fetched per league, as
+// a guard against an endpoint that never returns an empty page.
+const maxLeaguePages = 100
+
+// leagueTiers maps the league-v4 league paths to the tiers of league-exp-v4.
+var leagueTiers = map[string]string{
+	cutoff.LeagueChallenger:  "CHALLENGER",
+	cutoff.LeagueGrandmaster: "GRANDMASTER",
+	cutoff.LeagueMaster:      "MASTER",
+}
+
+// fetchPages fetches league through the paginated league-exp-v4 entries
+// endpoint, requesting pages until one comes back empty, and concatenates
+// them. Entries that moved across a page boundary between requests may show
+// up twice; cutoff.CreateLadder drops the duplicates. The league is only
+// NotModified when every page is, the empty page ending it included, as the
+// league could otherwise have gained or lost a page.
+func (f *Fetcher) fetchPages(ctx context.Context, region, league, queueType string) (cutoff.LeagueResponse, error) {
+	tier, ok := leagueTiers[league]
+	if !ok {
+		return cutoff.LeagueResponse{}, fmt.Errorf("no league-exp tier for league %s", league)
+	}
+
+	all := cutoff.LeagueResponse{NotModified: true}
+	for page := 1; page <= maxLeaguePages; page++ {
+		url := fmt.Sprintf("%s/lol/league-exp/v4/entries/%s/%s/I?page=%d", f.regionURL(region), queueType, tier, page)
+		cacheKey := fmt.Sprintf("%s_%s_%s_%d", region, queueType, league, page)
+		resp, err := f.get(ctx, region, league, url, cacheKey, func(body []byte) (cutoff.LeagueResponse, error) {
+			var entries []cutoff.LeagueEntry
+			err := json.Unmarshal(body, &entries)
+			return cutoff.LeagueResponse{Entries: entries}, err
+		})
+		if err != nil {
+			return cutoff.LeagueResponse{}, err
+		}
+		all.NotModified = all.NotModified && resp.NotModified
+		if len(resp.Entries) == 0 {
+			return all, nil
+		}
+		all.Entries = append(all.Entries, resp.Entries...)
+	}
+	return cutoff.LeagueResponse{}, fmt.Errorf("league %s %s for %s has more than %d pages", league, queueType, region, maxLeaguePages)
+}
+
+// get requests url, part of league, from region and decodes the body with
+// decode. Responses carrying validators are cached under cacheKey and
+// revalidated with conditional requests.
+func (f *Fetcher) get(ctx context.Context, region, league, url, cacheKey string, decode func([]byte) (cutoff.LeagueResponse, error)) (cutoff.LeagueResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return cutoff.LeagueResponse{}, fmt.Errorf("build request for %s: %w", url, err)
+	}
+	req.Header.Set("X-Riot-Token", f.apiKey)
+	req.Header.Set("User-Agent", f.userAgent)
+
+	cached, haveCached := f.cache.get(cacheKey)
+	if haveCached {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	if f.limiter != nil {
+		if err := f.limiter.wait(ctx, region, league); err != nil {
+			return cutoff.LeagueResponse{}, fmt.Errorf("wait for rate limit for %s: %w", url, err)
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return cutoff.LeagueResponse{}, fmt.Errorf("HTTP GET aborted for %s: %w", url, ctxErr)
+		}
+		return cutoff.LeagueResponse{}, fmt.Errorf("HTTP GET error for %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	f.observe(resp.Header)
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return cutoff.LeagueResponse{}, fmt.Errorf("%w: status code %d for URL: %s", ErrUnauthorized, resp.StatusCode, url)
+	}
+	if resp.StatusCode == http.StatusNotModified && haveCached {
+		f.cache.refresh(cacheKey)
+		cached.response.NotModified = true
+		return cached.response, nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return cutoff.LeagueResponse{}, fmt.Errorf("%w: API request failed with status code: %d for URL: %s", ErrTransient, resp.StatusCode, url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return cutoff.LeagueResponse{}, fmt.Errorf("API request failed with status code: %d for URL: %s", resp.StatusCode, url)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return cutoff.LeagueResponse{}, fmt.Errorf("failed to read response body for %s: %w", url, err)
+	}
+
+	// During outages Riot can answer 200 with an HTML maintenance page, so
+	// a body that isn't JSON is reported as transient and only quoted in
+	// part.
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
+			return cutoff.LeagueResponse{}, fmt.Errorf("%w: unexpected content type %q for %s - body: %s", ErrTransient, contentType, url, bodySnippet(body))
+		}
+	}
+
+	leagueData, err := decode(body)
+	if err != nil {
+		return cutoff.LeagueResponse{}, fmt.Errorf("%w: failed to unmarshal response body for %s: %w - body: %s", ErrTransient, url, err, bodySnippet(body))
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag != "" || lastModified != "" {
+		f.cache.put(cacheKey, cachedResponse{
+			etag:         etag,
+			lastModified: lastModified,
+			response:     leagueData,
+			storedAt:     time.Now(),
+		})
+	}
+
+	return leagueData, nil
+}
+
+// bodySnippet returns body for inclusion in an error message, truncated to
+// maxBodySnippet bytes.
+func bodySnippet(body []byte) string {
+	if len(body) <= maxBodySnippet {
+		return string(body)
+	}
+	return fmt.Sprintf("%s... (%d bytes total)", strings.ToValidUTF8(string(body[:maxBodySnippet]), ""), len(body))
+}
@@ -0,0 +1,425 @@
+package riot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/renja-g/lol-lp-cutoff/pkg/cutoff"
+)
+
+// testFetcher returns a Fetcher sending its requests to a test server serving
+// handler.
+func testFetcher(t *testing.T, handler http.HandlerFunc) *Fetcher {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewFetcher("test-key", "lp-cutoff-test", srv.URL, srv.Client(), nil, false)
+}
+
+// writeJSON writes body as a JSON response.
+func writeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(body))
+}
+
+func fetchChallenger(f *Fetcher) (cutoff.LeagueResponse, error) {
+	return f.Fetch(context.Background(), "euw1", cutoff.LeagueChallenger, cutoff.QueueSoloDuo)
+}
+
+func TestFetchRejectedKey(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			f := testFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"status":{"message":"Forbidden"}}`, status)
+			})
+			_, err := fetchChallenger(f)
+			if !errors.Is(err, ErrUnauthorized) {
+				t.Fatalf("err = %v, want ErrUnauthorized", err)
+			}
+			if errors.Is(err, ErrTransient) {
+				t.Errorf("err = %v is transient, want a rejected key not to be retried", err)
+			}
+		})
+	}
+}
+
+func TestFetchSendsHeaders(t *testing.T) {
+	var got http.Header
+	f := testFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		writeJSON(w, `{"entries":[]}`)
+	})
+	if _, err := fetchChallenger(f); err != nil {
+		t.Fatal(err)
+	}
+	if ua := got.Get("User-Agent"); ua != "lp-cutoff-test" {
+		t.Errorf("User-Agent = %q, want %q", ua, "lp-cutoff-test")
+	}
+	if key := got.Get("X-Riot-Token"); key != "test-key" {
+		t.Errorf("X-Riot-Token = %q, want %q", key, "test-key")
+	}
+}
+
+func TestFetchRevalidatesWithETag(t *testing.T) {
+	const etag = `"v1"`
+	var requests, notModified int
+	f := testFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		writeJSON(w, `{"entries":[{"puuid":"a","leaguePoints":1200},{"puuid":"b","leaguePoints":900}]}`)
+	})
+
+	first, err := fetchChallenger(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.NotModified {
+		t.Error("first response is marked not modified")
+	}
+	second, err := fetchChallenger(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || notModified != 1 {
+		t.Fatalf("%d requests with %d revalidated, want the second one revalidated", requests, notModified)
+	}
+	if !second.NotModified {
+		t.Error("revalidated response is not marked not modified")
+	}
+	if len(second.Entries) != 2 || second.Entries[0].LeaguePoints != 1200 {
+		t.Errorf("revalidated entries = %+v, want the cached ones", second.Entries)
+	}
+}
+
+func TestFetchMaintenancePage(t *testing.T) {
+	page := "<html><body>" + strings.Repeat("Down for maintenance. ", 200) + "</body></html>"
+	tests := []struct {
+		name        string
+		contentType string
+	}{
+		{"HTML content type", "text/html; charset=utf-8"},
+		{"JSON content type", "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := testFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(page))
+			})
+			_, err := fetchChallenger(f)
+			if !errors.Is(err, ErrTransient) {
+				t.Fatalf("err = %v, want ErrTransient", err)
+			}
+			msg := err.Error()
+			if strings.Contains(msg, page) {
+				t.Error("error quotes the whole body")
+			}
+			if !strings.Contains(msg, page[:maxBodySnippet]) || !strings.Contains(msg, fmt.Sprintf("(%d bytes total)", len(page))) {
+				t.Errorf("error = %q, want the first %d bytes and the body size", msg, maxBodySnippet)
+			}
+		})
+	}
+}
+
+func TestBodySnippet(t *testing.T) {
+	short := "short body"
+	if got := bodySnippet([]byte(short)); got != short {
+		t.Errorf("bodySnippet(%q) = %q, want it unchanged", short, got)
+	}
+	// A multi-byte rune cut at the limit is dropped rather than split.
+	long := strings.Repeat("a", maxBodySnippet-1) + "é" + "tail"
+	if got := bodySnippet([]byte(long)); !strings.HasPrefix(got, strings.Repeat("a", maxBodySnippet-1)+"...") || !utf8.ValidString(got) {
+		t.Errorf("bodySnippet of a long body = %q", got)
+	}
+}
+
+func TestFetchPages(t *testing.T) {
+	pages := map[string]string{
+		"1": `[{"puuid":"a","leaguePoints":1500},{"puuid":"b","leaguePoints":1400}]`,
+		// b dropped a place between the requests and shows up again.
+		"2": `[{"puuid":"b","leaguePoints":1400},{"puuid":"c","leaguePoints":1300}]`,
+		"3": `[{"puuid":"d","leaguePoints":1200}]`,
+		"4": `[]`,
+	}
+	var requested []string
+	f := testFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/lol/league-exp/v4/entries/RANKED_SOLO_5x5/CHALLENGER/I" {
+			t.Errorf("requested %s, want the league-exp entries", r.URL.Path)
+		}
+		page := r.URL.Query().Get("page")
+		requested = append(requested, page)
+		writeJSON(w, pages[page])
+	})
+	f.paginated = true
+
+	resp, err := fetchChallenger(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1", "2", "3", "4"}; !slices.Equal(requested, want) {
+		t.Errorf("requested pages %v, want %v", requested, want)
+	}
+	if len(resp.Entries) != 5 {
+		t.Errorf("%d entries, want all 5 of the pages", len(resp.Entries))
+	}
+	ladder := cutoff.CreateLadder(resp, cutoff.LeagueResponse{}, cutoff.LeagueResponse{})
+	var ids []string
+	for _, entry := range ladder {
+		ids = append(ids, entry.PUUID)
+	}
+	if want := []string{"a", "b", "c", "d"}; !slices.Equal(ids, want) {
+		t.Errorf("ladder = %v, want %v without the duplicate", ids, want)
+	}
+}
+
+func TestFetchPagesFailedPage(t *testing.T) {
+	f := testFetcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, `[{"puuid":"a","leaguePoints":1500}]`)
+	})
+	f.paginated = true
+	if _, err := fetchChallenger(f); !errors.Is(err, ErrTransient) {
+		t.Errorf("err = %v, want the failed page's ErrTransient", err)
+	}
+}
+
+func TestNewClientUsesProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		writeJSON(w, `{"entries":[{"puuid":"a","leaguePoints":1200}]}`)
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// riot.invalid doesn't resolve, so the league can only come from the
+	// proxy.
+	f := NewFetcher("test-key", "lp-cutoff-test", "http://{platform}.riot.invalid", NewClient(proxyURL, Timeouts{}), nil, false)
+	resp, err := fetchChallenger(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "http://euw1.riot.invalid/lol/league/v4/challengerleagues/by-queue/RANKED_SOLO_5x5"
+	if !slices.Equal(proxied, []string{want}) {
+		t.Errorf("proxy received %v, want %s", proxied, want)
+	}
+	if len(resp.Entries) != 1 {
+		t.Errorf("entries = %+v, want the proxy's response", resp.Entries)
+	}
+}
+
+func TestFetchRegionBaseURLs(t *testing.T) {
+	servers := make(map[string]string)
+	var hits []string
+	for _, name := range []string{"default", "gateway"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits = append(hits, name+r.URL.Path[:len("/lol/league")])
+			writeJSON(w, `{"entries":[]}`)
+		}))
+		t.Cleanup(srv.Close)
+		servers[name] = srv.URL
+	}
+	f := NewFetcher("test-key", "lp-cutoff-test", servers["default"], http.DefaultClient, nil, false)
+	f.SetRegionBaseURLs(map[string]string{"kr": servers["gateway"]})
+
+	for _, region := range []string{"euw1", "kr"} {
+		if _, err := f.Fetch(context.Background(), region, cutoff.LeagueChallenger, cutoff.QueueSoloDuo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"default/lol/league", "gateway/lol/league"}; !slices.Equal(hits, want) {
+		t.Errorf("hits = %v, want %v", hits, want)
+	}
+
+	// A reload without the override sends kr to the default again.
+	f.SetRegionBaseURLs(nil)
+	hits = nil
+	if _, err := f.Fetch(context.Background(), "kr", cutoff.LeagueChallenger, cutoff.QueueSoloDuo); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"default/lol/league"}; !slices.Equal(hits, want) {
+		t.Errorf("hits after dropping the override = %v, want %v", hits, want)
+	}
+}
+
+func TestPlatformURL(t *testing.T) {
+	tests := []struct {
+		base, want string
+	}{
+		{DefaultBaseURL, "https://euw1." + DefaultBaseURL},
+		{"http://127.0.0.1:8080/", "http://127.0.0.1:8080"},
+		{"https://{platform}.gateway.example/riot", "https://euw1.gateway.example/riot"},
+	}
+	for _, tt := range tests {
+		if got := platformURL(tt.base, "euw1"); got != tt.want {
+			t.Errorf("platformURL(%q) = %q, want %q", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-header" {
+			<-release
+			return
+		}
+		// Headers go out at once, the body trickles in after the timeout.
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "done")
+	}))
+	defer srv.Close()
+	defer close(release)
+	client := NewClient(nil, Timeouts{ResponseHeader: 50 * time.Millisecond})
+
+	start := time.Now()
+	_, err := client.Get(srv.URL + "/slow-header")
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request failed after %s, want it to fail at the header timeout", elapsed)
+	}
+
+	resp, err := client.Get(srv.URL + "/slow-body")
+	if err != nil {
+		t.Fatalf("slow body: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, err := io.ReadAll(resp.Body); err != nil || string(body) != "done" {
+		t.Errorf("slow body = %q, %v, want it read past the header timeout", body, err)
+	}
+}
+
+// versionedPages serves every league-exp league as two pages of entries and
+// an empty third, each with an ETag holding its version, and answers 304 to
+// requests revalidating the current version.
+type versionedPages struct {
+	mu       sync.Mutex
+	versions map[string]int
+}
+
+func (p *versionedPages) bump(page string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.versions[page]++
+}
+
+func (p *versionedPages) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page := r.URL.Query().Get("page")
+	p.mu.Lock()
+	etag := fmt.Sprintf(`"%s-%s-%d"`, r.URL.Path, page, p.versions[page])
+	p.mu.Unlock()
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	switch page {
+	case "1":
+		writeJSON(w, `[{"puuid":"a","leaguePoints":1500},{"puuid":"b","leaguePoints":1400}]`)
+	case "2":
+		writeJSON(w, `[{"puuid":"c","leaguePoints":1300}]`)
+	default:
+		writeJSON(w, `[]`)
+	}
+}
+
+func TestFetchPagesNotModified(t *testing.T) {
+	tests := []struct {
+		name            string
+		changed         string
+		wantNotModified bool
+	}{
+		{"every page unchanged", "", true},
+		{"one page changed", "2", false},
+		{"ending page changed", "3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := &versionedPages{versions: make(map[string]int)}
+			f := testFetcher(t, pages.ServeHTTP)
+			f.paginated = true
+			if first, err := fetchChallenger(f); err != nil || first.NotModified {
+				t.Fatalf("first fetch NotModified = %t, err = %v, want a fresh league", first.NotModified, err)
+			}
+
+			if tt.changed != "" {
+				pages.bump(tt.changed)
+			}
+			second, err := fetchChallenger(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second.NotModified != tt.wantNotModified {
+				t.Errorf("NotModified = %t, want %t", second.NotModified, tt.wantNotModified)
+			}
+			if len(second.Entries) != 3 {
+				t.Errorf("entries = %+v, want all 3 of the pages", second.Entries)
+			}
+		})
+	}
+}
+
+func TestPaginatedUnchangedLeaguesSkipCompute(t *testing.T) {
+	pages := &versionedPages{versions: make(map[string]int)}
+	f := testFetcher(t, pages.ServeHTTP)
+	f.paginated = true
+	queues := cutoff.Queues{
+		SoloDuo: cutoff.QueueConfig{Challenger: 2, Grandmaster: 2},
+		Flex:    cutoff.QueueConfig{Challenger: 2, Grandmaster: 2},
+	}
+	first, err := cutoff.ComputeRegion(context.Background(), f, "euw1", queues, cutoff.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cutoffs no ladder of the test leagues yields, so reusing them shows
+	// the ladder wasn't rebuilt.
+	previous := first
+	previous.RANKED_SOLO_5x5.Challenger = 4242
+	previous.RANKED_FLEX_SR.Challenger = 4242
+	second, err := cutoff.ComputeRegion(context.Background(), f, "euw1", queues, cutoff.Options{Previous: &previous})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RANKED_SOLO_5x5.Challenger != 4242 || second.RANKED_FLEX_SR.Challenger != 4242 {
+		t.Errorf("Challenger cutoffs = %d/%d, want the previous 4242 reused for unchanged pages",
+			second.RANKED_SOLO_5x5.Challenger, second.RANKED_FLEX_SR.Challenger)
+	}
+
+	pages.bump("2")
+	third, err := cutoff.ComputeRegion(context.Background(), f, "euw1", queues, cutoff.Options{Previous: &previous})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.RANKED_SOLO_5x5.Challenger != first.RANKED_SOLO_5x5.Challenger {
+		t.Errorf("Challenger cutoff after a page changed = %d, want it recomputed as %d", third.RANKED_SOLO_5x5.Challenger, first.RANKED_SOLO_5x5.Challenger)
+	}
+}